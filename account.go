@@ -6,7 +6,9 @@
 package acd
 
 import (
+	"context"
 	"net/http"
+	"net/url"
 	"time"
 )
 
@@ -26,8 +28,8 @@ type AccountInfo struct {
 
 // Provides information about the current user account like the status and the
 // accepted “Terms Of Use”.
-func (s *AccountService) GetInfo() (*AccountInfo, *http.Response, error) {
-	req, err := s.client.NewMetadataRequest("GET", "account/info", nil)
+func (s *AccountService) GetInfo(ctx context.Context) (*AccountInfo, *http.Response, error) {
+	req, err := s.client.NewMetadataRequest(ctx, "GET", "account/info", nil)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -49,8 +51,8 @@ type AccountQuota struct {
 }
 
 // Gets account quota and storage availability information.
-func (s *AccountService) GetQuota() (*AccountQuota, *http.Response, error) {
-	req, err := s.client.NewMetadataRequest("GET", "account/quota", nil)
+func (s *AccountService) GetQuota(ctx context.Context) (*AccountQuota, *http.Response, error) {
+	req, err := s.client.NewMetadataRequest(ctx, "GET", "account/quota", nil)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -82,8 +84,8 @@ type UsageNumbers struct {
 }
 
 // Gets Account Usage information broken down by content category.
-func (s *AccountService) GetUsage() (*AccountUsage, *http.Response, error) {
-	req, err := s.client.NewMetadataRequest("GET", "account/usage", nil)
+func (s *AccountService) GetUsage(ctx context.Context) (*AccountUsage, *http.Response, error) {
+	req, err := s.client.NewMetadataRequest(ctx, "GET", "account/usage", nil)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -96,3 +98,39 @@ func (s *AccountService) GetUsage() (*AccountUsage, *http.Response, error) {
 
 	return accountUsage, resp, err
 }
+
+// AccountEndpoint represents the customer-specific endpoints of an Amazon Cloud
+// Drive account.
+type AccountEndpoint struct {
+	CustomerExists *bool   `json:"customerExists"`
+	ContentURL     *string `json:"contentUrl"`
+	MetadataURL    *string `json:"metadataUrl"`
+}
+
+// Gets the metadata and content endpoints assigned to the current user. Set
+// Client.EndpointTTL to have the client use them automatically.
+func (s *AccountService) GetEndpoint(ctx context.Context) (*AccountEndpoint, *http.Response, error) {
+	// querying the endpoints needs no discovery, use the current URL
+	s.client.endpointsMu.Lock()
+	metadataURL := s.client.MetadataURL
+	s.client.endpointsMu.Unlock()
+
+	return s.getEndpoint(ctx, metadataURL)
+}
+
+// getEndpoint gets the endpoints by querying the given metadata URL. It does
+// not trigger endpoint discovery itself.
+func (s *AccountService) getEndpoint(ctx context.Context, metadataURL *url.URL) (*AccountEndpoint, *http.Response, error) {
+	req, err := s.client.newRequest(ctx, metadataURL, "GET", "account/endpoint", nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	accountEndpoint := &AccountEndpoint{}
+	resp, err := s.client.Do(req, accountEndpoint)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return accountEndpoint, resp, err
+}
@@ -6,7 +6,9 @@
 package acd
 
 import (
+	"context"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -15,7 +17,7 @@ func TestAccount_getInfo(t *testing.T) {
 	r := *NewMockResponseOkString(`{ "termsOfUse": "1.0.0", "status": "ACTIVE" }`)
 	c := NewMockClient(r)
 
-	info, _, err := c.Account.GetInfo()
+	info, _, err := c.Account.GetInfo(context.Background())
 
 	assert.NoError(t, err)
 	assert.Equal(t, "ACTIVE", *info.Status)
@@ -32,7 +34,7 @@ func TestAccount_getQuota(t *testing.T) {
 	`)
 	c := NewMockClient(r)
 
-	quota, _, err := c.Account.GetQuota()
+	quota, _, err := c.Account.GetQuota(context.Background())
 
 	assert.NoError(t, err)
 	assert.Equal(t, "2014-08-13 23:01:47.479 +0000 UTC", quota.LastCalculated.String())
@@ -88,7 +90,7 @@ func TestAccount_getUsage(t *testing.T) {
 	`)
 	c := NewMockClient(r)
 
-	usage, _, err := c.Account.GetUsage()
+	usage, _, err := c.Account.GetUsage(context.Background())
 
 	assert.NoError(t, err)
 	assert.Equal(t, "2014-08-13 23:17:41.365 +0000 UTC", usage.LastCalculated.String())
@@ -113,3 +115,86 @@ func TestAccount_getUsage(t *testing.T) {
 	assert.Equal(t, uint64(23524252), *usage.Video.Billable.Bytes)
 	assert.Equal(t, uint64(22), *usage.Video.Billable.Count)
 }
+
+func TestAccount_getEndpoint(t *testing.T) {
+	r := *NewMockResponseOkString(`
+{
+	"customerExists": true,
+	"contentUrl": "https://content-eu.drive.amazonaws.com/cdproxy/",
+	"metadataUrl": "https://cdws.eu-west-1.amazonaws.com/drive/v1/"
+}
+	`)
+	c := NewMockClient(r)
+
+	endpoint, _, err := c.Account.GetEndpoint(context.Background())
+
+	assert.NoError(t, err)
+	assert.True(t, *endpoint.CustomerExists)
+	assert.Equal(t, "https://content-eu.drive.amazonaws.com/cdproxy/", *endpoint.ContentURL)
+	assert.Equal(t, "https://cdws.eu-west-1.amazonaws.com/drive/v1/", *endpoint.MetadataURL)
+}
+
+func TestAccount_endpointDiscovery(t *testing.T) {
+	c := NewMockClientSequence(
+		*NewMockResponseOkString(`
+{
+	"customerExists": true,
+	"contentUrl": "https://content-eu.drive.amazonaws.com/cdproxy",
+	"metadataUrl": "https://cdws.eu-west-1.amazonaws.com/drive/v1"
+}
+		`),
+		*NewMockResponseOkString(`{ "termsOfUse": "1.0.0", "status": "ACTIVE" }`),
+	)
+	c.EndpointTTL = time.Hour
+
+	_, _, err := c.Account.GetInfo(context.Background())
+
+	assert.NoError(t, err)
+	reqs := mockTransportOf(c).reqs
+	assert.Equal(t, 2, len(reqs))
+	assert.Equal(t, "https://drive.amazonaws.com/drive/v1/account/endpoint", reqs[0].URL.String())
+	assert.Equal(t, "https://cdws.eu-west-1.amazonaws.com/drive/v1/account/info", reqs[1].URL.String())
+	assert.Equal(t, "https://content-eu.drive.amazonaws.com/cdproxy/", c.ContentURL.String())
+
+	// endpoints are cached until the TTL expires
+	_, _, err = c.Account.GetInfo(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(mockTransportOf(c).reqs))
+}
+
+func TestAccount_endpointRefreshBackoff(t *testing.T) {
+	c := NewMockClientRoutes(map[string]MockResponse{
+		"/drive/v1/account/endpoint": MockResponse{Code: 500},
+		"/drive/v1/account/info":     *NewMockResponseOkString(`{ "termsOfUse": "1.0.0", "status": "ACTIVE" }`),
+	})
+	c.RetryPolicy = nil
+	c.EndpointTTL = time.Hour
+	c.endpointsFetched = time.Now().Add(-2 * time.Hour)
+
+	for i := 0; i < 4; i++ {
+		_, _, err := c.Account.GetInfo(context.Background())
+		assert.NoError(t, err)
+	}
+
+	// a single failed refresh, then the stale endpoints are used
+	reqs := mockRouteTransportOf(c).reqs
+	assert.Equal(t, 5, len(reqs))
+	assert.Equal(t, "/drive/v1/account/endpoint", reqs[0].URL.Path)
+}
+
+func TestAccount_getEndpointWithTTL(t *testing.T) {
+	r := *NewMockResponseOkString(`
+{
+	"customerExists": true,
+	"contentUrl": "https://content-eu.drive.amazonaws.com/cdproxy/",
+	"metadataUrl": "https://cdws.eu-west-1.amazonaws.com/drive/v1/"
+}
+	`)
+	c := NewMockClient(r)
+	c.EndpointTTL = time.Hour
+
+	_, _, err := c.Account.GetEndpoint(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(mockTransportOf(c).reqs))
+}
@@ -0,0 +1,382 @@
+// Copyright (c) 2015 Serge Gebhardt. All rights reserved.
+//
+// Use of this source code is governed by the ISC
+// license that can be found in the LICENSE file.
+
+package acd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// NodeCache is a local cache of node metadata, indexed by id and by parent
+// and name. Assign it to NodesService.Cache to have lookups by id or name,
+// GetRoot and GetAllChildren served from the cache when possible. The cache is
+// populated by listings and kept up to date by NodesService.SyncCache, which
+// should be called regularly. It is safe for concurrent use.
+type NodeCache struct {
+	mu         sync.RWMutex
+	nodes      map[string]*Node             // by id
+	children   map[string]map[string]string // parent id -> name -> child id
+	complete   map[string]bool              // parent ids with all children cached
+	full       bool                         // whether all nodes are cached
+	rootId     string
+	checkpoint string
+
+	store CacheStore
+}
+
+// CacheStore persists the content of a NodeCache.
+type CacheStore interface {
+	Load() (*CacheSnapshot, error)
+	Save(*CacheSnapshot) error
+}
+
+// CacheSnapshot is the persisted content of a NodeCache.
+type CacheSnapshot struct {
+	Checkpoint string   `json:"checkpoint"`
+	Full       bool     `json:"full"`
+	Complete   []string `json:"complete"`
+	Nodes      []*Node  `json:"nodes"`
+}
+
+// NewNodeCache returns an empty in-memory NodeCache.
+func NewNodeCache() *NodeCache {
+	c := &NodeCache{}
+	c.reset()
+	return c
+}
+
+// OpenNodeCache returns a NodeCache persisted in store, loading its current
+// content. The cache is saved to the store by NodesService.SyncCache and by
+// Save.
+func OpenNodeCache(store CacheStore) (*NodeCache, error) {
+	c := NewNodeCache()
+	c.store = store
+
+	snapshot, err := store.Load()
+	if err != nil {
+		return nil, err
+	}
+	if snapshot == nil {
+		return c, nil
+	}
+
+	c.checkpoint = snapshot.Checkpoint
+	c.full = snapshot.Full
+	for _, n := range snapshot.Nodes {
+		c.put(n)
+	}
+	for _, id := range snapshot.Complete {
+		c.complete[id] = true
+	}
+	return c, nil
+}
+
+// Save writes the content of the cache to its store, if any.
+func (c *NodeCache) Save() error {
+	if c.store == nil {
+		return nil
+	}
+
+	c.mu.RLock()
+	snapshot := &CacheSnapshot{
+		Checkpoint: c.checkpoint,
+		Full:       c.full,
+		Complete:   make([]string, 0, len(c.complete)),
+		Nodes:      make([]*Node, 0, len(c.nodes)),
+	}
+	for id := range c.complete {
+		snapshot.Complete = append(snapshot.Complete, id)
+	}
+	for _, n := range c.nodes {
+		snapshot.Nodes = append(snapshot.Nodes, n)
+	}
+	c.mu.RUnlock()
+
+	return c.store.Save(snapshot)
+}
+
+// Checkpoint returns the changes checkpoint the cache is up to date with.
+func (c *NodeCache) Checkpoint() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.checkpoint
+}
+
+// Len returns the number of cached nodes.
+func (c *NodeCache) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return len(c.nodes)
+}
+
+// reset empties the cache. The caller must hold mu, or have exclusive access.
+func (c *NodeCache) reset() {
+	c.nodes = make(map[string]*Node)
+	c.children = make(map[string]map[string]string)
+	c.complete = make(map[string]bool)
+	c.full = false
+	c.rootId = ""
+	c.checkpoint = ""
+}
+
+// put adds or replaces node n, or removes it if it is no longer available.
+// The caller must hold mu, or have exclusive access.
+func (c *NodeCache) put(n *Node) {
+	if n.Id == nil {
+		return
+	}
+
+	c.remove(*n.Id)
+	if !isAvailable(n) {
+		return
+	}
+
+	c.nodes[*n.Id] = cloneNode(n, nil)
+
+	if n.IsRoot != nil && *n.IsRoot {
+		c.rootId = *n.Id
+	}
+	if n.Name != nil {
+		for _, p := range n.Parents {
+			if c.children[p] == nil {
+				c.children[p] = make(map[string]string)
+			}
+			c.children[p][*n.Name] = *n.Id
+		}
+	}
+}
+
+// isAvailable returns whether node n is neither trashed nor purged. The API
+// is not consistent in the case of the status.
+func isAvailable(n *Node) bool {
+	if n.Status == nil {
+		return true
+	}
+	return !strings.EqualFold(*n.Status, "TRASH") && !strings.EqualFold(*n.Status, "PURGED")
+}
+
+// remove removes the node with the given id. The caller must hold mu, or have
+// exclusive access.
+func (c *NodeCache) remove(id string) {
+	old, ok := c.nodes[id]
+	if !ok {
+		return
+	}
+
+	delete(c.nodes, id)
+	if old.Name != nil {
+		for _, p := range old.Parents {
+			if c.children[p][*old.Name] == id {
+				delete(c.children[p], *old.Name)
+			}
+		}
+	}
+}
+
+// Put adds or replaces the given nodes in the cache. Nodes which are not
+// available any more (e.g. trashed) are removed.
+func (c *NodeCache) Put(nodes ...*Node) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, n := range nodes {
+		c.put(n)
+	}
+}
+
+// Remove removes the node with the given id from the cache.
+func (c *NodeCache) Remove(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.remove(id)
+}
+
+// putChildren replaces the cached children of the given parent by nodes.
+func (c *NodeCache) putChildren(parentId string, nodes []*Node) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, id := range c.children[parentId] {
+		c.remove(id)
+	}
+	for _, n := range nodes {
+		c.put(n)
+	}
+	c.complete[parentId] = true
+}
+
+// get returns a copy of the cached node with the given id, bound to s.
+func (c *NodeCache) get(s *NodesService, id string) (*Node, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.getLocked(s, id)
+}
+
+func (c *NodeCache) getLocked(s *NodesService, id string) (*Node, bool) {
+	n, ok := c.nodes[id]
+	if !ok {
+		return nil, false
+	}
+
+	return cloneNode(n, s), true
+}
+
+// cloneNode returns a copy of n bound to s, not sharing the slices of n.
+func cloneNode(n *Node, s *NodesService) *Node {
+	cp := *n
+	cp.Parents = append([]string(nil), n.Parents...)
+	cp.Labels = append([]string(nil), n.Labels...)
+	cp.service = s
+	return &cp
+}
+
+// root returns the root folder, if cached.
+func (c *NodeCache) root(s *NodesService) (*Node, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.rootId == "" {
+		return nil, false
+	}
+	return c.getLocked(s, c.rootId)
+}
+
+// child looks up the child name of the given parent, if cached.
+func (c *NodeCache) child(s *NodesService, parentId, name string) (*Node, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	id, ok := c.children[parentId][name]
+	if !ok {
+		return nil, false
+	}
+	return c.getLocked(s, id)
+}
+
+// listChildren returns the children of the given parent, sorted by name, if
+// all of them are cached.
+func (c *NodeCache) listChildren(s *NodesService, parentId string) ([]*Node, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if !c.full && !c.complete[parentId] {
+		return nil, false
+	}
+
+	names := make([]string, 0, len(c.children[parentId]))
+	for name := range c.children[parentId] {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	nodes := make([]*Node, 0, len(names))
+	for _, name := range names {
+		if n, ok := c.getLocked(s, c.children[parentId][name]); ok {
+			nodes = append(nodes, n)
+		}
+	}
+	return nodes, true
+}
+
+// SyncCache brings the cache up to date by applying the changes since its
+// checkpoint, and saves it to its store. Starting from an empty cache, this
+// fetches all nodes. Errors if there is no cache.
+func (s *NodesService) SyncCache(ctx context.Context) error {
+	c := s.Cache
+	if c == nil {
+		return errors.New("No node cache configured")
+	}
+
+	changes, _, err := s.GetChanges(ctx, c.Checkpoint(), &ChangesOptions{IncludePurged: true})
+	if err != nil {
+		return err
+	}
+	defer changes.Close()
+
+	fromScratch := c.Checkpoint() == ""
+	for changes.Next() {
+		cs := changes.ChangeSet()
+
+		c.mu.Lock()
+		if cs.Reset {
+			c.reset()
+			fromScratch = true
+		}
+		for _, n := range cs.Nodes {
+			c.put(n)
+		}
+		c.checkpoint = changes.Checkpoint()
+		c.mu.Unlock()
+	}
+
+	if changes.Ended() && fromScratch {
+		c.mu.Lock()
+		c.full = true
+		c.mu.Unlock()
+	}
+
+	if err := c.Save(); err != nil {
+		return err
+	}
+	return changes.Err()
+}
+
+// FileCacheStore is a CacheStore persisting the cache as a single JSON file.
+type FileCacheStore struct {
+	Path string
+}
+
+// Load implements CacheStore. Returns a nil snapshot if the file does not
+// exist yet.
+func (s *FileCacheStore) Load() (*CacheSnapshot, error) {
+	data, err := ioutil.ReadFile(s.Path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := &CacheSnapshot{}
+	if err := json.Unmarshal(data, snapshot); err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+// Save implements CacheStore. The file is replaced atomically.
+func (s *FileCacheStore) Save(snapshot *CacheSnapshot) error {
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(s.Path), filepath.Base(s.Path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.Path)
+}
@@ -0,0 +1,171 @@
+// Copyright (c) 2015 Serge Gebhardt. All rights reserved.
+//
+// Use of this source code is governed by the ISC
+// license that can be found in the LICENSE file.
+
+package acd
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testChanges = `{"checkpoint":"cp1","nodes":[
+	{"id":"root","kind":"FOLDER","isRoot":true,"status":"AVAILABLE"},
+	{"id":"a","name":"a","kind":"FOLDER","parents":["root"],"status":"AVAILABLE"},
+	{"id":"b","name":"b.txt","kind":"FILE","parents":["a"],"status":"AVAILABLE"},
+	{"id":"c","name":"c.txt","kind":"FILE","parents":["a"],"status":"AVAILABLE"}
+],"reset":false,"statusCode":200}
+{"end":true}
+`
+
+func TestCache_childrenListing(t *testing.T) {
+	c := NewMockClientSequence(
+		*NewMockResponseOkString(`{ "count": 2, "data": [
+		{ "id": "b", "name": "b.txt", "kind": "FILE", "parents": ["a"] },
+		{ "id": "c", "name": "c.txt", "kind": "FILE", "parents": ["a"] }
+	] }`),
+		*NewMockResponseOkString(`{ "count": 0, "data": [] }`),
+	)
+	c.Nodes.Cache = NewNodeCache()
+	id := "a"
+	folder := &Folder{&Node{Id: &id, service: c.Nodes}}
+
+	nodes, _, err := folder.GetAllChildren(context.Background(), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(nodes))
+	assert.Equal(t, 1, len(mockTransportOf(c).reqs))
+
+	// served from the cache
+	nodes, resp, err := folder.GetAllChildren(context.Background(), nil)
+	assert.NoError(t, err)
+	assert.Nil(t, resp)
+	assert.Equal(t, "b.txt", *nodes[0].Name)
+	assert.Equal(t, "c.txt", *nodes[1].Name)
+
+	n, _, err := folder.GetNode(context.Background(), "c.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, "c", *n.Id)
+
+	assert.Equal(t, 1, len(mockTransportOf(c).reqs))
+
+	// misses are checked with the server
+	_, _, err = folder.GetNode(context.Background(), "missing")
+	assert.True(t, IsNotFound(err))
+	assert.Equal(t, 2, len(mockTransportOf(c).reqs))
+}
+
+func TestCache_sync(t *testing.T) {
+	c := NewMockClientSequence(
+		*NewMockResponseOkString(testChanges),
+		*NewMockResponseOkString(`{ "count": 0, "data": [] }`),
+	)
+	c.Nodes.Cache = NewNodeCache()
+
+	err := c.Nodes.SyncCache(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "cp1", c.Nodes.Cache.Checkpoint())
+	assert.Equal(t, 4, c.Nodes.Cache.Len())
+
+	// path resolution is served from the cache
+	f, err := c.Nodes.Stat(context.Background(), "/a/b.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, "b", *f.(*File).Id)
+
+	assert.Equal(t, 1, len(mockTransportOf(c).reqs))
+
+	_, err = c.Nodes.Stat(context.Background(), "/a/missing")
+	assert.True(t, IsNotFound(err))
+	assert.Equal(t, 2, len(mockTransportOf(c).reqs))
+}
+
+func TestCache_mixedCaseStatus(t *testing.T) {
+	cache := NewNodeCache()
+	id, name, status := "a", "a.txt", "Available"
+	cache.Put(&Node{Id: &id, Name: &name, Parents: []string{"root"}, Status: &status})
+	assert.Equal(t, 1, cache.Len())
+
+	trashed := "trash"
+	cache.Put(&Node{Id: &id, Name: &name, Parents: []string{"root"}, Status: &trashed})
+	assert.Equal(t, 0, cache.Len())
+}
+
+func TestCache_mkdirAllRace(t *testing.T) {
+	// the cache knows all children of root, but another client created "b"
+	c := NewMockClientSequence(
+		*NewMockResponseOkString(`{ "count": 0, "data": [] }`),
+		MockResponse{Code: 409},
+		*NewMockResponseOkString(`{ "count": 1, "data": [
+			{ "id": "b", "name": "b", "kind": "FOLDER", "parents": ["root"] }
+		] }`),
+	)
+	c.RetryPolicy = nil
+	c.Nodes.Cache = NewNodeCache()
+	c.Nodes.Cache.putChildren("root", nil)
+	id := "root"
+	root := &Folder{&Node{Id: &id, service: c.Nodes}}
+
+	f, _, err := root.MkdirAll(context.Background(), "b")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "b", *f.Id)
+	assert.Equal(t, "POST", mockTransportOf(c).reqs[1].Method)
+}
+
+func TestCache_syncApplyChanges(t *testing.T) {
+	c := NewMockClientSequence(
+		*NewMockResponseOkString(testChanges),
+		*NewMockResponseOkString(`{"checkpoint":"cp2","nodes":[
+	{"id":"b","name":"renamed.txt","kind":"FILE","parents":["a"],"status":"AVAILABLE"},
+	{"id":"c","name":"c.txt","kind":"FILE","parents":["a"],"status":"TRASH"}
+],"reset":false,"statusCode":200}
+{"end":true}
+`),
+	)
+	c.Nodes.Cache = NewNodeCache()
+
+	assert.NoError(t, c.Nodes.SyncCache(context.Background()))
+	assert.NoError(t, c.Nodes.SyncCache(context.Background()))
+	assert.Equal(t, `{"checkpoint":"cp1","includePurged":true}`+"\n", string(mockTransportOf(c).bodies[1]))
+
+	id := "a"
+	folder := &Folder{&Node{Id: &id, service: c.Nodes}}
+	nodes, _, err := folder.GetAllChildren(context.Background(), nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(nodes))
+	assert.Equal(t, "renamed.txt", *nodes[0].Name)
+	assert.Equal(t, 2, len(mockTransportOf(c).reqs))
+}
+
+func TestCache_fileStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go-acd")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	store := &FileCacheStore{Path: filepath.Join(dir, "cache.json")}
+	cache, err := OpenNodeCache(store)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, cache.Len())
+
+	c := NewMockClient(*NewMockResponseOkString(testChanges))
+	c.Nodes.Cache = cache
+	assert.NoError(t, c.Nodes.SyncCache(context.Background()))
+
+	reopened, err := OpenNodeCache(store)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, reopened.Len())
+	assert.Equal(t, "cp1", reopened.Checkpoint())
+
+	c = NewMockClient(MockResponse{Code: 500})
+	c.Nodes.Cache = reopened
+	f, err := c.Nodes.Stat(context.Background(), "/a/c.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, "c", *f.(*File).Id)
+	assert.Equal(t, 0, len(mockTransportOf(c).reqs))
+}
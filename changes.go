@@ -0,0 +1,166 @@
+// Copyright (c) 2015 Serge Gebhardt. All rights reserved.
+//
+// Use of this source code is governed by the ISC
+// license that can be found in the LICENSE file.
+
+package acd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+)
+
+// ChangesOptions holds the options when getting changes.
+type ChangesOptions struct {
+	// Maximum number of nodes per change set. Zero uses the API default.
+	ChunkSize int `json:"chunkSize,omitempty"`
+
+	// Maximum number of nodes in the whole response. Zero uses the API
+	// default.
+	MaxNodes int `json:"maxNodes,omitempty"`
+
+	// Whether to include purged nodes.
+	IncludePurged bool `json:"includePurged,omitempty"`
+}
+
+// ChangeSet represents a set of modified nodes, as returned by the changes
+// API.
+type ChangeSet struct {
+	// Checkpoint to request the next changes from, once this change set has
+	// been processed.
+	Checkpoint string `json:"checkpoint"`
+
+	// Modified nodes. Trashed and purged nodes are included, see Node.Status.
+	Nodes []*Node `json:"nodes"`
+
+	// Reset indicates that the checkpoint passed to GetChanges is no longer
+	// valid. All local state must be discarded, the changes start from
+	// scratch.
+	Reset bool `json:"reset"`
+
+	StatusCode int `json:"statusCode"`
+}
+
+// changesInternal is a single JSON object of a changes response, either a
+// change set or the end marker.
+type changesInternal struct {
+	ChangeSet
+	End bool `json:"end"`
+}
+
+// GetChanges gets the changes to nodes since checkpoint. An empty checkpoint
+// gets all nodes. opts may be nil. The change sets are streamed and must be
+// iterated with the returned Changes, which the caller must close.
+//
+// See: https://developer.amazon.com/public/apis/experience/cloud-drive/content/changes
+func (s *NodesService) GetChanges(ctx context.Context, checkpoint string, opts *ChangesOptions) (*Changes, *http.Response, error) {
+	body := struct {
+		Checkpoint string `json:"checkpoint,omitempty"`
+		*ChangesOptions
+	}{checkpoint, opts}
+
+	req, err := s.client.NewMetadataRequest(ctx, "POST", "changes", body)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	resp, err := s.client.do(req)
+	if err != nil {
+		if resp != nil {
+			resp.Body.Close()
+		}
+		return nil, resp, err
+	}
+
+	changes := &Changes{
+		service:    s,
+		body:       resp.Body,
+		dec:        json.NewDecoder(resp.Body),
+		checkpoint: checkpoint,
+	}
+	return changes, resp, nil
+}
+
+// Changes iterates over the change sets of a changes response:
+//
+//	for changes.Next() {
+//		cs := changes.ChangeSet()
+//		...
+//	}
+//	if err := changes.Err(); err != nil {
+//		...
+//	}
+type Changes struct {
+	service    *NodesService
+	body       io.ReadCloser
+	dec        *json.Decoder
+	cur        *ChangeSet
+	checkpoint string
+	end        bool
+	err        error
+}
+
+// Next advances to the next change set, which is then available through
+// ChangeSet. Returns false when there are no more change sets or an error
+// occurred.
+func (c *Changes) Next() bool {
+	if c.end || c.err != nil {
+		return false
+	}
+
+	ci := &changesInternal{}
+	if err := c.dec.Decode(ci); err != nil {
+		if err == io.EOF {
+			err = errors.New("Changes ended without end marker")
+		}
+		c.err = err
+		c.cur = nil
+		return false
+	}
+
+	if ci.End {
+		c.end = true
+		c.cur = nil
+		return false
+	}
+
+	for _, n := range ci.Nodes {
+		n.service = c.service
+	}
+	if ci.Checkpoint != "" {
+		c.checkpoint = ci.Checkpoint
+	}
+	c.cur = &ci.ChangeSet
+	return true
+}
+
+// ChangeSet returns the current change set.
+func (c *Changes) ChangeSet() *ChangeSet {
+	return c.cur
+}
+
+// Checkpoint returns the checkpoint of the last change set read, or the one
+// passed to GetChanges if none was read yet. Persist it to continue from
+// there later.
+func (c *Changes) Checkpoint() string {
+	return c.checkpoint
+}
+
+// Ended returns whether the end marker was reached, i.e. all changes up to
+// now were received.
+func (c *Changes) Ended() bool {
+	return c.end
+}
+
+// Err returns the error which stopped the iteration, if any.
+func (c *Changes) Err() error {
+	return c.err
+}
+
+// Close releases the underlying response.
+func (c *Changes) Close() error {
+	return c.body.Close()
+}
@@ -0,0 +1,60 @@
+// Copyright (c) 2015 Serge Gebhardt. All rights reserved.
+//
+// Use of this source code is governed by the ISC
+// license that can be found in the LICENSE file.
+
+package acd
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChanges_getChanges(t *testing.T) {
+	r := *NewMockResponseOkString(`{"checkpoint":"cp1","nodes":[{"id":"a","name":"a.txt","kind":"FILE","status":"AVAILABLE"},{"id":"b","kind":"FOLDER","status":"TRASH"}],"reset":true,"statusCode":200}
+{"checkpoint":"cp2","nodes":[{"id":"c","kind":"FILE"}],"reset":false,"statusCode":200}
+{"end":true}
+`)
+	c := NewMockClient(r)
+
+	changes, _, err := c.Nodes.GetChanges(context.Background(), "cp0", &ChangesOptions{ChunkSize: 2})
+	assert.NoError(t, err)
+	defer changes.Close()
+
+	assert.Equal(t, `{"checkpoint":"cp0","chunkSize":2}`+"\n", string(mockTransportOf(c).bodies[0]))
+	assert.Equal(t, "cp0", changes.Checkpoint())
+
+	assert.True(t, changes.Next())
+	cs := changes.ChangeSet()
+	assert.True(t, cs.Reset)
+	assert.Equal(t, 2, len(cs.Nodes))
+	assert.Equal(t, "TRASH", *cs.Nodes[1].Status)
+	assert.Equal(t, "cp1", changes.Checkpoint())
+
+	assert.True(t, changes.Next())
+	assert.False(t, changes.ChangeSet().Reset)
+	assert.Equal(t, "c", *changes.ChangeSet().Nodes[0].Id)
+
+	assert.False(t, changes.Next())
+	assert.NoError(t, changes.Err())
+	assert.True(t, changes.Ended())
+	assert.Equal(t, "cp2", changes.Checkpoint())
+}
+
+func TestChanges_truncated(t *testing.T) {
+	r := *NewMockResponseOkString(`{"checkpoint":"cp1","nodes":[],"reset":false,"statusCode":200}
+`)
+	c := NewMockClient(r)
+
+	changes, _, err := c.Nodes.GetChanges(context.Background(), "", nil)
+	assert.NoError(t, err)
+	defer changes.Close()
+
+	assert.Equal(t, "{}\n", string(mockTransportOf(c).bodies[0]))
+	assert.True(t, changes.Next())
+	assert.False(t, changes.Next())
+	assert.Error(t, changes.Err())
+	assert.False(t, changes.Ended())
+}
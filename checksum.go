@@ -0,0 +1,98 @@
+// Copyright (c) 2015 Serge Gebhardt. All rights reserved.
+//
+// Use of this source code is governed by the ISC
+// license that can be found in the LICENSE file.
+
+package acd
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+)
+
+// ErrChecksumMismatch is matched by ChecksumMismatchError.
+var ErrChecksumMismatch = errors.New("Checksum mismatch")
+
+// ChecksumMismatchError is returned when the MD5 of transferred content does
+// not match the MD5 reported by the Amazon Cloud Drive. It matches
+// ErrChecksumMismatch.
+type ChecksumMismatchError struct {
+	// Local path or node name the content was transferred from or to.
+	Name string
+
+	// Id of the node, if known.
+	NodeId string
+
+	Expected string
+	Actual   string
+}
+
+func (e *ChecksumMismatchError) Error() string {
+	return fmt.Sprintf("MD5 mismatch for '%s': expected %s, got %s", e.Name, e.Expected, e.Actual)
+}
+
+// Is reports whether target is ErrChecksumMismatch. Used by errors.Is.
+func (e *ChecksumMismatchError) Is(target error) bool {
+	return target == ErrChecksumMismatch
+}
+
+// IsChecksumMismatch reports whether err is a ChecksumMismatchError.
+func IsChecksumMismatch(err error) bool {
+	return errors.Is(err, ErrChecksumMismatch)
+}
+
+// contentMD5 returns the MD5 of the content of node n, or "" if unknown.
+func contentMD5(n *Node) string {
+	if n.ContentProperties == nil || n.ContentProperties.MD5 == nil {
+		return ""
+	}
+	return *n.ContentProperties.MD5
+}
+
+// checkMD5 returns a ChecksumMismatchError if the MD5 summed by h differs
+// from the MD5 of the content of node n. Nodes without a known MD5 pass.
+func checkMD5(n *Node, h hash.Hash, name string) error {
+	expected := contentMD5(n)
+	if expected == "" {
+		return nil
+	}
+
+	actual := hex.EncodeToString(h.Sum(nil))
+	if actual == expected {
+		return nil
+	}
+
+	err := &ChecksumMismatchError{Name: name, Expected: expected, Actual: actual}
+	if n.Id != nil {
+		err.NodeId = *n.Id
+	}
+	return err
+}
+
+// verifyingReadCloser hashes the content read through it and returns a
+// ChecksumMismatchError instead of io.EOF if it does not match the node's MD5.
+type verifyingReadCloser struct {
+	io.ReadCloser
+	node *Node
+	name string
+	hash hash.Hash
+}
+
+func newVerifyingReadCloser(rc io.ReadCloser, n *Node, name string) io.ReadCloser {
+	return &verifyingReadCloser{rc, n, name, md5.New()}
+}
+
+func (r *verifyingReadCloser) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.hash.Write(p[:n])
+	if err == io.EOF {
+		if cerr := checkMD5(r.node, r.hash, r.name); cerr != nil {
+			return n, cerr
+		}
+	}
+	return n, err
+}
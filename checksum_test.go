@@ -0,0 +1,143 @@
+// Copyright (c) 2015 Serge Gebhardt. All rights reserved.
+//
+// Use of this source code is governed by the ISC
+// license that can be found in the LICENSE file.
+
+package acd
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFile_downloadVerified(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go-acd")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	sum := fmt.Sprintf("%x", md5.Sum([]byte(testContent)))
+	c := NewMockContentClient([]byte(testContent))
+	f := newTestFile(c, nil)
+	f.ContentProperties = &ContentProperties{MD5: &sum}
+
+	path := filepath.Join(dir, "foo")
+	_, err = f.Download(context.Background(), path)
+
+	assert.NoError(t, err)
+	data, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, testContent, string(data))
+}
+
+func TestFile_downloadMismatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go-acd")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// server content truncated
+	sum := fmt.Sprintf("%x", md5.Sum([]byte(testContent)))
+	c := NewMockContentClient([]byte(testContent[:10]))
+	f := newTestFile(c, nil)
+	f.ContentProperties = &ContentProperties{MD5: &sum}
+
+	path := filepath.Join(dir, "foo")
+	_, err = f.Download(context.Background(), path)
+
+	assert.True(t, IsChecksumMismatch(err))
+	var cerr *ChecksumMismatchError
+	assert.True(t, errors.As(err, &cerr))
+	assert.Equal(t, path, cerr.Name)
+	assert.Equal(t, "fooo1", cerr.NodeId)
+	assert.Equal(t, sum, cerr.Expected)
+	assert.Equal(t, fmt.Sprintf("%x", md5.Sum([]byte(testContent[:10]))), cerr.Actual)
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+}
+
+// brokenBodyTransport is a mocked Transport whose response bodies fail with err
+// after content.
+type brokenBodyTransport struct {
+	content []byte
+	err     error
+}
+
+// Satisfies the RoundTripper interface.
+func (t *brokenBodyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body := io.MultiReader(bytes.NewReader(t.content), iotest.ErrReader(t.err))
+	return &http.Response{
+		StatusCode: 200,
+		Header:     http.Header{},
+		Request:    req,
+		Body:       ioutil.NopCloser(body),
+	}, nil
+}
+
+func TestFile_downloadInterrupted(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go-acd")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	broken := errors.New("connection reset")
+	c := NewClient(&http.Client{Transport: &brokenBodyTransport{[]byte(testContent[:10]), broken}})
+	f := newTestFile(c, nil)
+
+	path := filepath.Join(dir, "foo")
+	_, err = f.Download(context.Background(), path)
+
+	assert.Equal(t, broken, err)
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestFile_openMismatch(t *testing.T) {
+	sum := fmt.Sprintf("%x", md5.Sum([]byte("something else")))
+	c := NewMockContentClient([]byte(testContent))
+	f := newTestFile(c, nil)
+	f.ContentProperties = &ContentProperties{MD5: &sum}
+
+	body, _, err := f.Open(context.Background())
+	assert.NoError(t, err)
+	defer body.Close()
+
+	data, err := ioutil.ReadAll(body)
+	assert.True(t, IsChecksumMismatch(err))
+	assert.Equal(t, testContent, string(data))
+}
+
+func TestFolder_uploadMismatch(t *testing.T) {
+	sum := fmt.Sprintf("%x", md5.Sum([]byte("something else")))
+	c := NewMockClientRoutes(map[string]MockResponse{
+		"POST /cdproxy/nodes":         *NewMockResponseOkString(`{ "id": "newFile", "name": "bar", "kind": "FILE", "contentProperties": { "md5": "` + sum + `" } }`),
+		"PUT /drive/v1/trash/newFile": *NewMockResponseOkString(`{ "id": "newFile", "name": "bar", "kind": "FILE", "status": "TRASH" }`),
+	})
+	id := "parentId"
+	folder := &Folder{&Node{Id: &id, service: c.Nodes}}
+
+	opts := &UploadOptions{TrashOnMismatch: true}
+	file, _, err := folder.UploadReader(context.Background(), strings.NewReader(testContent), "bar", opts)
+
+	var cerr *ChecksumMismatchError
+	assert.True(t, errors.As(err, &cerr))
+	assert.Equal(t, "bar", cerr.Name)
+	assert.Equal(t, "newFile", cerr.NodeId)
+	assert.Equal(t, fmt.Sprintf("%x", md5.Sum([]byte(testContent))), cerr.Actual)
+	assert.Equal(t, "newFile", *file.Id)
+
+	reqs := mockRouteTransportOf(c).reqs
+	last := reqs[len(reqs)-1]
+	assert.Equal(t, "PUT", last.Method)
+	assert.Equal(t, "/drive/v1/trash/newFile", last.URL.Path)
+}
@@ -7,6 +7,7 @@ package acd
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -14,6 +15,9 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"strings"
+	"sync"
+	"time"
 )
 
 const (
@@ -39,6 +43,20 @@ type Client struct {
 	// User agent used when communicating with the API.
 	UserAgent string
 
+	// Time-to-live of the customer-specific endpoints. If non-zero, MetadataURL
+	// and ContentURL are discovered via the account/endpoint API before the
+	// first request and refreshed once they are older than EndpointTTL.
+	EndpointTTL time.Duration
+
+	endpointsMu      sync.Mutex
+	endpointsFetched time.Time
+	endpointsRetryAt time.Time // no refresh before, after a failed one
+	endpointsErr     error     // error of the last failed refresh
+
+	// Retry policy applied to failed requests in Do. Defaults to
+	// DefaultRetryPolicy(). Set to nil to disable retries.
+	RetryPolicy *RetryPolicy
+
 	// Services used for talking to different parts of the API.
 	Account *AccountService
 	Nodes   *NodesService
@@ -60,6 +78,7 @@ func NewClient(httpClient *http.Client) *Client {
 		MetadataURL: metadataURL,
 		ContentURL:  contentURL,
 		UserAgent:   userAgent,
+		RetryPolicy: DefaultRetryPolicy(),
 	}
 
 	c.Account = &AccountService{client: c}
@@ -72,18 +91,111 @@ func NewClient(httpClient *http.Client) *Client {
 // provided in urlStr, in which case it is resolved relative to the MetadataURL
 // of the Client. Relative URLs should always be specified without a preceding
 // slash. If specified, the value pointed to by body is JSON encoded and included
-// as the request body.
-func (c *Client) NewMetadataRequest(method, urlStr string, body interface{}) (*http.Request, error) {
-	return c.newRequest(c.MetadataURL, method, urlStr, body)
+// as the request body. The request is bound to ctx, which cancels it when done.
+func (c *Client) NewMetadataRequest(ctx context.Context, method, urlStr string, body interface{}) (*http.Request, error) {
+	metadataURL, _, err := c.endpoints(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return c.newRequest(ctx, metadataURL, method, urlStr, body)
 }
 
 // NewContentRequest creates an API request for content. A relative URL can be
 // provided in urlStr, in which case it is resolved relative to the ContentURL
 // of the Client. Relative URLs should always be specified without a preceding
 // slash. If specified, the value pointed to by body is JSON encoded and included
-// as the request body.
-func (c *Client) NewContentRequest(method, urlStr string, body interface{}) (*http.Request, error) {
-	return c.newRequest(c.ContentURL, method, urlStr, body)
+// as the request body. The request is bound to ctx, which cancels it when done.
+func (c *Client) NewContentRequest(ctx context.Context, method, urlStr string, body interface{}) (*http.Request, error) {
+	_, contentURL, err := c.endpoints(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return c.newRequest(ctx, contentURL, method, urlStr, body)
+}
+
+// RefreshEndpoints discovers the customer-specific metadata and content URLs
+// via the account/endpoint API and updates MetadataURL and ContentURL
+// accordingly.
+func (c *Client) RefreshEndpoints(ctx context.Context) error {
+	c.endpointsMu.Lock()
+	defer c.endpointsMu.Unlock()
+
+	return c.refreshEndpoints(ctx)
+}
+
+// endpointRetryDelay is the time to wait after a failed endpoint discovery
+// before trying again. Capped at EndpointTTL.
+const endpointRetryDelay = time.Minute
+
+// endpoints returns the metadata and content URLs to use for requests. If
+// EndpointTTL is set, the URLs are (re)discovered first when they are stale.
+// Failing to refresh previously discovered URLs is not an error, the stale
+// URLs are used until the next successful refresh. After a failed refresh,
+// the next attempt is delayed by endpointRetryDelay.
+func (c *Client) endpoints(ctx context.Context) (metadataURL, contentURL *url.URL, err error) {
+	c.endpointsMu.Lock()
+	defer c.endpointsMu.Unlock()
+
+	if c.EndpointTTL > 0 && time.Since(c.endpointsFetched) > c.EndpointTTL {
+		err := c.endpointsErr
+		if !time.Now().Before(c.endpointsRetryAt) {
+			err = c.refreshEndpoints(ctx)
+			if err != nil && ctx.Err() == nil {
+				delay := endpointRetryDelay
+				if delay > c.EndpointTTL {
+					delay = c.EndpointTTL
+				}
+				c.endpointsRetryAt = time.Now().Add(delay)
+				c.endpointsErr = err
+			}
+		}
+		if err != nil && c.endpointsFetched.IsZero() {
+			return nil, nil, err
+		}
+	}
+
+	return c.MetadataURL, c.ContentURL, nil
+}
+
+// refreshEndpoints does the work of RefreshEndpoints. The caller must hold
+// endpointsMu.
+func (c *Client) refreshEndpoints(ctx context.Context) error {
+	endpoint, _, err := c.Account.getEndpoint(ctx, c.MetadataURL)
+	if err != nil {
+		return err
+	}
+
+	if endpoint.CustomerExists != nil && !*endpoint.CustomerExists {
+		return errors.New("No Amazon Cloud Drive customer for this account")
+	}
+	if endpoint.MetadataURL == nil || endpoint.ContentURL == nil {
+		return errors.New("Incomplete endpoint information")
+	}
+
+	metadataURL, err := parseBaseURL(*endpoint.MetadataURL)
+	if err != nil {
+		return err
+	}
+	contentURL, err := parseBaseURL(*endpoint.ContentURL)
+	if err != nil {
+		return err
+	}
+
+	c.MetadataURL = metadataURL
+	c.ContentURL = contentURL
+	c.endpointsFetched = time.Now()
+	c.endpointsRetryAt = time.Time{}
+	c.endpointsErr = nil
+	return nil
+}
+
+// parseBaseURL parses a base URL, adding the trailing slash expected by
+// newRequest if it is missing.
+func parseBaseURL(s string) (*url.URL, error) {
+	if !strings.HasSuffix(s, "/") {
+		s += "/"
+	}
+	return url.Parse(s)
 }
 
 // newRequest creates an API request. A relative URL can be provided in urlStr,
@@ -91,7 +203,7 @@ func (c *Client) NewContentRequest(method, urlStr string, body interface{}) (*ht
 // Relative URLs should always be specified without a preceding slash. If
 // specified, the value pointed to by body is JSON encoded and included as the
 // request body.
-func (c *Client) newRequest(base *url.URL, method, urlStr string, body interface{}) (*http.Request, error) {
+func (c *Client) newRequest(ctx context.Context, base *url.URL, method, urlStr string, body interface{}) (*http.Request, error) {
 	rel, err := url.Parse(urlStr)
 	if err != nil {
 		return nil, err
@@ -109,11 +221,23 @@ func (c *Client) newRequest(base *url.URL, method, urlStr string, body interface
 		bodyReader = buf
 	}
 
-	req, err := http.NewRequest(method, u.String(), bodyReader)
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bodyReader)
 	if err != nil {
 		return nil, err
 	}
 
+	// allow Do to rewind seekable bodies when retrying the request
+	if seeker, ok := bodyReader.(io.ReadSeeker); ok && req.GetBody == nil {
+		offset, err := seeker.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return nil, err
+		}
+		req.GetBody = func() (io.ReadCloser, error) {
+			_, err := seeker.Seek(offset, io.SeekStart)
+			return ioutil.NopCloser(seeker), err
+		}
+	}
+
 	//	req.Header.Add("Accept", mediaTypeV3)
 	if c.UserAgent != "" {
 		req.Header.Add("User-Agent", c.UserAgent)
@@ -125,16 +249,13 @@ func (c *Client) newRequest(base *url.URL, method, urlStr string, body interface
 // JSON decoded and stored in the value pointed to by v, or returned as an
 // error if an API error has occurred. If v implements the io.Writer
 // interface, the raw response body will be written to v, without attempting to
-// first decode it.
+// first decode it. Requests failing with a transient error are retried
+// according to the Client's RetryPolicy, unless the request's context is done.
 func (c *Client) Do(req *http.Request, v interface{}) (*http.Response, error) {
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, err
+	resp, err := c.do(req)
+	if resp != nil {
+		defer resp.Body.Close()
 	}
-
-	defer resp.Body.Close()
-
-	err = CheckResponse(resp)
 	if err != nil {
 		// even though there was an error, we still return the response
 		// in case the caller wants to inspect it further
@@ -143,7 +264,7 @@ func (c *Client) Do(req *http.Request, v interface{}) (*http.Response, error) {
 
 	if v != nil {
 		if w, ok := v.(io.Writer); ok {
-			io.Copy(w, resp.Body)
+			_, err = io.Copy(w, resp.Body)
 		} else {
 			err = json.NewDecoder(resp.Body).Decode(v)
 		}
@@ -151,27 +272,168 @@ func (c *Client) Do(req *http.Request, v interface{}) (*http.Response, error) {
 	return resp, err
 }
 
+// do sends req, retrying as long as the RetryPolicy allows it, and returns the
+// last response along with its error as determined by CheckResponse.
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		resp, err := c.httpClient.Do(req)
+		if err == nil {
+			err = CheckResponse(resp)
+		}
+		if err == nil {
+			return resp, nil
+		}
+
+		if !c.RetryPolicy.shouldRetry(req, resp, attempt) {
+			return resp, err
+		}
+
+		if resp != nil {
+			drainBody(resp.Body)
+		}
+
+		timer := time.NewTimer(c.RetryPolicy.delay(resp, attempt))
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+
+		if err := rewindBody(req); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// ErrorResponse reports an error caused by an API request. It carries the HTTP
+// status code, the error details decoded from the Cloud Drive JSON error body
+// (if any) as well as the raw body.
+type ErrorResponse struct {
+	// HTTP response that caused this error.
+	Response *http.Response `json:"-"`
+
+	// HTTP status code of the response.
+	StatusCode int `json:"-"`
+
+	// Method and URL of the request that caused this error.
+	Method string   `json:"-"`
+	URL    *url.URL `json:"-"`
+
+	// Error details as returned by the API.
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	LogRef  string `json:"logref"`
+
+	// Additional details, such as the id of the existing node on a 409.
+	Info struct {
+		NodeId string `json:"nodeId"`
+	} `json:"info"`
+
+	// Raw response body.
+	Body []byte `json:"-"`
+}
+
+func (r *ErrorResponse) Error() string {
+	msg := fmt.Sprintf("HTTP code %v", r.StatusCode)
+	if r.Method != "" && r.URL != nil {
+		msg = fmt.Sprintf("%v %v: %v", r.Method, r.URL, msg)
+	}
+
+	switch {
+	case r.Code != "" && r.Message != "":
+		msg += fmt.Sprintf(", %v: %v", r.Code, r.Message)
+	case r.Code != "":
+		msg += fmt.Sprintf(", %v", r.Code)
+	case r.Message != "":
+		msg += fmt.Sprintf(", %v", r.Message)
+	case len(r.Body) > 0:
+		msg += fmt.Sprintf(", response body: %s", r.Body)
+	default:
+		msg += ", no response body"
+	}
+
+	if r.LogRef != "" {
+		msg += fmt.Sprintf(" (logref %v)", r.LogRef)
+	}
+
+	return msg
+}
+
+// Is reports whether the error matches one of the sentinel errors ErrNotFound,
+// ErrConflict, ErrThrottled or ErrUnauthorized. Used by errors.Is.
+func (r *ErrorResponse) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return r.StatusCode == http.StatusNotFound
+	case ErrConflict:
+		return r.StatusCode == http.StatusConflict
+	case ErrThrottled:
+		return r.StatusCode == http.StatusTooManyRequests
+	case ErrUnauthorized:
+		return r.StatusCode == http.StatusUnauthorized
+	}
+	return false
+}
+
+// Sentinel errors matched by an *ErrorResponse with the corresponding HTTP
+// status code. Use errors.Is or the Is* helpers to test for them.
+var (
+	ErrNotFound     = errors.New("acd: not found")
+	ErrConflict     = errors.New("acd: conflict")
+	ErrThrottled    = errors.New("acd: throttled")
+	ErrUnauthorized = errors.New("acd: unauthorized")
+)
+
+// IsNotFound reports whether err was caused by a 404 (Not Found) response.
+func IsNotFound(err error) bool {
+	return errors.Is(err, ErrNotFound)
+}
+
+// IsConflict reports whether err was caused by a 409 (Conflict) response, e.g.
+// a name collision.
+func IsConflict(err error) bool {
+	return errors.Is(err, ErrConflict)
+}
+
+// IsThrottled reports whether err was caused by a 429 (Too Many Requests)
+// response.
+func IsThrottled(err error) bool {
+	return errors.Is(err, ErrThrottled)
+}
+
+// IsUnauthorized reports whether err was caused by a 401 (Unauthorized)
+// response.
+func IsUnauthorized(err error) bool {
+	return errors.Is(err, ErrUnauthorized)
+}
+
 // CheckResponse checks the API response for errors, and returns them if
 // present.  A response is considered an error if it has a status code outside
-// the 200 range.
+// the 200 range. The returned error is of type *ErrorResponse.
 func CheckResponse(r *http.Response) error {
 	c := r.StatusCode
 	if 200 <= c && c <= 299 {
 		return nil
 	}
 
-	errBody := ""
-	if data, err := ioutil.ReadAll(r.Body); err != nil {
-		errBody = string(data)
+	errorResponse := &ErrorResponse{
+		Response:   r,
+		StatusCode: c,
+	}
+	if r.Request != nil {
+		errorResponse.Method = r.Request.Method
+		errorResponse.URL = r.Request.URL
 	}
 
-	errMsg := fmt.Sprintf("HTTP code %v, ", c)
-	if errBody == "" {
-		errMsg += "no response body"
-	} else {
-		errMsg += fmt.Sprintf("reponse body: %v", errBody)
+	if r.Body != nil {
+		data, err := ioutil.ReadAll(r.Body)
+		if err == nil && len(data) > 0 {
+			errorResponse.Body = data
+			// not all error bodies are JSON, keep the raw body in that case
+			json.Unmarshal(data, errorResponse)
+		}
 	}
-	errMsg += "\n"
 
-	return errors.New(errMsg)
+	return errorResponse
 }
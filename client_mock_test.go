@@ -7,14 +7,18 @@ package acd
 
 import (
 	"bytes"
+	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
+	"sync"
 )
 
 // MockResponse is a static HTTP response.
 type MockResponse struct {
-	Code int
-	Body []byte
+	Code   int
+	Body   []byte
+	Header http.Header
 }
 
 // NewMockResponseOkString creates a new MockResponse with Code 200 (OK)
@@ -26,23 +30,42 @@ func NewMockResponseOkString(response string) *MockResponse {
 	}
 }
 
-// mockTransport is a mocked Transport that always returns the same MockResponse.
+// mockTransport is a mocked Transport that returns the MockResponses in
+// sequence, repeating the last one once the sequence is exhausted. It records
+// all requests and their bodies.
 type mockTransport struct {
-	resp MockResponse
+	resps  []MockResponse
+	reqs   []*http.Request
+	bodies [][]byte
 }
 
 // Satisfies the RoundTripper interface.
 func (t *mockTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		body, _ = ioutil.ReadAll(req.Body)
+		req.Body.Close()
+	}
+
+	i := len(t.reqs)
+	if i >= len(t.resps) {
+		i = len(t.resps) - 1
+	}
+	t.reqs = append(t.reqs, req)
+	t.bodies = append(t.bodies, body)
+
+	resp := t.resps[i]
 	r := http.Response{
-		StatusCode: t.resp.Code,
+		StatusCode: resp.Code,
 		Proto:      "HTTP/1.0",
 		ProtoMajor: 1,
 		ProtoMinor: 0,
+		Header:     resp.Header,
+		Request:    req,
+		Body:       ioutil.NopCloser(bytes.NewBuffer(resp.Body)),
 	}
-
-	if len(t.resp.Body) > 0 {
-		buf := bytes.NewBuffer(t.resp.Body)
-		r.Body = ioutil.NopCloser(buf)
+	if r.Header == nil {
+		r.Header = http.Header{}
 	}
 
 	return &r, nil
@@ -50,7 +73,121 @@ func (t *mockTransport) RoundTrip(req *http.Request) (*http.Response, error) {
 
 // MockClient is a mocked Client that is used for tests.
 func NewMockClient(response MockResponse) *Client {
-	t := &mockTransport{resp: response}
+	return NewMockClientSequence(response)
+}
+
+// NewMockClientSequence returns a mocked Client which answers requests with
+// the given responses in order.
+func NewMockClientSequence(responses ...MockResponse) *Client {
+	t := &mockTransport{resps: responses}
 	c := &http.Client{Transport: t}
 	return NewClient(c)
 }
+
+// mockTransportOf returns the mockTransport used by a mocked Client.
+func mockTransportOf(c *Client) *mockTransport {
+	return c.httpClient.Transport.(*mockTransport)
+}
+
+// mockContentTransport is a mocked Transport serving static content, honoring
+// Range request headers of the form "bytes=a-" and "bytes=a-b".
+type mockContentTransport struct {
+	content []byte
+	reqs    []*http.Request
+}
+
+// Satisfies the RoundTripper interface.
+func (t *mockContentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.reqs = append(t.reqs, req)
+
+	r := http.Response{
+		StatusCode: 200,
+		Proto:      "HTTP/1.0",
+		ProtoMajor: 1,
+		ProtoMinor: 0,
+		Header:     http.Header{},
+		Request:    req,
+	}
+
+	body := t.content
+	if rng := req.Header.Get("Range"); rng != "" {
+		var start, end int
+		n, _ := fmt.Sscanf(rng, "bytes=%d-%d", &start, &end)
+		if n < 2 || end >= len(t.content) {
+			end = len(t.content) - 1
+		}
+		if start >= len(t.content) {
+			r.StatusCode = 416
+			body = nil
+		} else {
+			r.StatusCode = 206
+			r.Header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, len(t.content)))
+			body = t.content[start : end+1]
+		}
+	}
+
+	r.ContentLength = int64(len(body))
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	return &r, nil
+}
+
+// NewMockContentClient returns a mocked Client which serves content for every
+// request.
+func NewMockContentClient(content []byte) *Client {
+	t := &mockContentTransport{content: content}
+	c := &http.Client{Transport: t}
+	return NewClient(c)
+}
+
+// mockRouteTransport is a mocked Transport returning the MockResponse
+// registered for the request's method and URL path (e.g. "POST /drive/v1/nodes")
+// or else for its URL path alone, or 404 if there is none.
+type mockRouteTransport struct {
+	routes map[string]MockResponse
+
+	mu   sync.Mutex
+	reqs []*http.Request
+}
+
+// Satisfies the RoundTripper interface.
+func (t *mockRouteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.mu.Lock()
+	t.reqs = append(t.reqs, req)
+	t.mu.Unlock()
+
+	if req.Body != nil {
+		io.Copy(ioutil.Discard, req.Body)
+		req.Body.Close()
+	}
+
+	resp, ok := t.routes[req.Method+" "+req.URL.Path]
+	if !ok {
+		resp, ok = t.routes[req.URL.Path]
+	}
+	if !ok {
+		resp = MockResponse{Code: 404}
+	}
+
+	return &http.Response{
+		StatusCode: resp.Code,
+		Proto:      "HTTP/1.0",
+		ProtoMajor: 1,
+		ProtoMinor: 0,
+		Header:     http.Header{},
+		Request:    req,
+		Body:       ioutil.NopCloser(bytes.NewBuffer(resp.Body)),
+	}, nil
+}
+
+// NewMockClientRoutes returns a mocked Client which answers requests with the
+// responses registered for their URL paths.
+func NewMockClientRoutes(routes map[string]MockResponse) *Client {
+	t := &mockRouteTransport{routes: routes}
+	c := &http.Client{Transport: t}
+	return NewClient(c)
+}
+
+// mockRouteTransportOf returns the mockRouteTransport used by a mocked Client.
+func mockRouteTransportOf(c *Client) *mockRouteTransport {
+	return c.httpClient.Transport.(*mockRouteTransport)
+}
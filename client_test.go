@@ -0,0 +1,95 @@
+// Copyright (c) 2015 Serge Gebhardt. All rights reserved.
+//
+// Use of this source code is governed by the ISC
+// license that can be found in the LICENSE file.
+
+package acd
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_errorResponse(t *testing.T) {
+	r := MockResponse{
+		Code: 409,
+		Body: []byte(`{"logref":"c7b4e2d2","message":"Node with the name foo already exists","code":"NAME_ALREADY_EXISTS"}`),
+	}
+	c := NewMockClient(r)
+
+	_, _, err := c.Account.GetInfo(context.Background())
+
+	assert.Error(t, err)
+	assert.True(t, IsConflict(err))
+	assert.False(t, IsNotFound(err))
+	assert.True(t, errors.Is(err, ErrConflict))
+
+	var errResp *ErrorResponse
+	assert.True(t, errors.As(err, &errResp))
+	assert.Equal(t, 409, errResp.StatusCode)
+	assert.Equal(t, "NAME_ALREADY_EXISTS", errResp.Code)
+	assert.Equal(t, "Node with the name foo already exists", errResp.Message)
+	assert.Equal(t, "c7b4e2d2", errResp.LogRef)
+	assert.Equal(t, "GET", errResp.Method)
+	assert.Equal(t, "https://drive.amazonaws.com/drive/v1/account/info", errResp.URL.String())
+	assert.Equal(t, r.Body, errResp.Body)
+}
+
+func TestClient_errorResponseNonJSON(t *testing.T) {
+	r := MockResponse{
+		Code: 503,
+		Body: []byte("Service Unavailable"),
+	}
+	c := NewMockClient(r)
+	c.RetryPolicy = nil
+
+	_, _, err := c.Account.GetInfo(context.Background())
+
+	var errResp *ErrorResponse
+	assert.True(t, errors.As(err, &errResp))
+	assert.Equal(t, 503, errResp.StatusCode)
+	assert.Equal(t, "", errResp.Code)
+	assert.Equal(t, []byte("Service Unavailable"), errResp.Body)
+	assert.Equal(t, "GET https://drive.amazonaws.com/drive/v1/account/info: HTTP code 503, response body: Service Unavailable", err.Error())
+}
+
+func TestErrorResponse_error(t *testing.T) {
+	tests := []struct {
+		r    ErrorResponse
+		want string
+	}{
+		{ErrorResponse{StatusCode: 400, Code: "INVALID_INPUT", Message: "bad"}, "HTTP code 400, INVALID_INPUT: bad"},
+		{ErrorResponse{StatusCode: 400, Code: "INVALID_INPUT"}, "HTTP code 400, INVALID_INPUT"},
+		{ErrorResponse{StatusCode: 400, Message: "bad"}, "HTTP code 400, bad"},
+		{ErrorResponse{StatusCode: 400, Message: "bad", LogRef: "c7b4e2d2"}, "HTTP code 400, bad (logref c7b4e2d2)"},
+		{ErrorResponse{StatusCode: 400}, "HTTP code 400, no response body"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, tt.r.Error())
+	}
+}
+
+func TestClient_errorResponseSentinels(t *testing.T) {
+	tests := []struct {
+		code  int
+		check func(error) bool
+	}{
+		{401, IsUnauthorized},
+		{404, IsNotFound},
+		{409, IsConflict},
+		{429, IsThrottled},
+	}
+
+	for _, tt := range tests {
+		c := NewMockClient(MockResponse{Code: tt.code})
+		c.RetryPolicy = nil
+		_, _, err := c.Account.GetInfo(context.Background())
+
+		assert.True(t, tt.check(err), "code %v", tt.code)
+		assert.False(t, IsConflict(err) && tt.code != 409, "code %v", tt.code)
+	}
+}
@@ -0,0 +1,515 @@
+// Copyright (c) 2015 Serge Gebhardt. All rights reserved.
+//
+// Use of this source code is governed by the ISC
+// license that can be found in the LICENSE file.
+
+package acd
+
+import (
+	"bufio"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Layout of encrypted content: a header holding the magic, the format version
+// and the per-file key wrapped by the master key, followed by the content in
+// chunks of encChunkSize bytes, each sealed with AES-GCM under the file key.
+// The nonce of a chunk is its index, with a flag marking the last chunk, so
+// that reordered, dropped or truncated chunks fail authentication.
+const (
+	encMagic      = "ACDE"
+	encVersion    = 1
+	encKeySize    = 32
+	encNonceSize  = 12
+	encOverhead   = 16
+	encChunkSize  = 64 << 10
+	encHeaderSize = len(encMagic) + 1 + encNonceSize + encKeySize + encOverhead
+)
+
+// ErrCiphertext is returned when decrypting content or a name which is not
+// valid ciphertext, has been tampered with, or was encrypted with another key.
+var ErrCiphertext = errors.New("Invalid or tampered encrypted content")
+
+// nameEncoding encodes encrypted names, lower case to survive case-insensitive
+// file systems.
+var nameEncoding = base32.NewEncoding("abcdefghijklmnopqrstuvwxyz234567").WithPadding(base32.NoPadding)
+
+// Cipher encrypts file content and names on the client, so the Amazon Cloud
+// Drive only stores ciphertext. Content is encrypted with a random key per
+// file, wrapped by the master key in the file header. Names are encrypted
+// deterministically, so that encrypted names can be looked up.
+type Cipher struct {
+	// Whether Upload encrypts the node name. Otherwise only the content is
+	// encrypted.
+	EncryptNames bool
+
+	wrap    cipher.AEAD // wraps the file keys
+	name    cipher.AEAD // encrypts the names
+	nameMAC []byte      // derives the nonces of the names
+}
+
+// GenerateKey returns a new random master key for NewCipher.
+func GenerateKey() ([]byte, error) {
+	key := make([]byte, encKeySize)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// NewCipher returns a Cipher with the given 32-byte master key.
+func NewCipher(masterKey []byte, encryptNames bool) (*Cipher, error) {
+	if len(masterKey) != encKeySize {
+		return nil, errors.New(fmt.Sprintf("Master key must be %d bytes, got %d", encKeySize, len(masterKey)))
+	}
+
+	wrap, err := newGCM(deriveKey(masterKey, "content key wrapping"))
+	if err != nil {
+		return nil, err
+	}
+	name, err := newGCM(deriveKey(masterKey, "name encryption"))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Cipher{
+		EncryptNames: encryptNames,
+		wrap:         wrap,
+		name:         name,
+		nameMAC:      deriveKey(masterKey, "name nonce"),
+	}, nil
+}
+
+// deriveKey derives a subkey of the master key for the given purpose.
+func deriveKey(masterKey []byte, purpose string) []byte {
+	mac := hmac.New(sha256.New, masterKey)
+	mac.Write([]byte("go-acd " + purpose))
+	return mac.Sum(nil)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// EncryptName returns the encrypted form of name. The same name always
+// encrypts to the same result, so it can be used with Folder.GetNode and
+// friends to look up encrypted nodes.
+func (c *Cipher) EncryptName(name string) string {
+	mac := hmac.New(sha256.New, c.nameMAC)
+	mac.Write([]byte(name))
+	nonce := mac.Sum(nil)[:encNonceSize]
+
+	sealed := c.name.Seal(nonce, nonce, []byte(name), nil)
+	return nameEncoding.EncodeToString(sealed)
+}
+
+// DecryptName returns the name encrypted by EncryptName.
+func (c *Cipher) DecryptName(encrypted string) (string, error) {
+	sealed, err := nameEncoding.DecodeString(encrypted)
+	if err != nil || len(sealed) < encNonceSize+encOverhead {
+		return "", ErrCiphertext
+	}
+
+	nonce := sealed[:encNonceSize]
+	name, err := c.name.Open(nil, nonce, sealed[encNonceSize:], nil)
+	if err != nil {
+		return "", ErrCiphertext
+	}
+	return string(name), nil
+}
+
+// nodeName returns the name under which name is stored on the drive.
+func (c *Cipher) nodeName(name string) string {
+	if c.EncryptNames {
+		return c.EncryptName(name)
+	}
+	return name
+}
+
+// Name returns the plain name of node n.
+func (c *Cipher) Name(n *Node) (string, error) {
+	if n.Name == nil {
+		return "", nil
+	}
+	if !c.EncryptNames {
+		return *n.Name, nil
+	}
+	return c.DecryptName(*n.Name)
+}
+
+// EncryptedSize returns the size of the encryption of size bytes of content.
+func EncryptedSize(size int64) int64 {
+	chunks := (size + encChunkSize - 1) / encChunkSize
+	if chunks == 0 {
+		chunks = 1
+	}
+	return int64(encHeaderSize) + size + chunks*encOverhead
+}
+
+// DecryptedSize returns the size of the content encrypted into size bytes.
+func DecryptedSize(size int64) (int64, error) {
+	body := size - int64(encHeaderSize)
+	if body < encOverhead {
+		return 0, ErrCiphertext
+	}
+
+	chunks := (body + encChunkSize + encOverhead - 1) / (encChunkSize + encOverhead)
+	if body-(chunks-1)*(encChunkSize+encOverhead) < encOverhead {
+		return 0, ErrCiphertext
+	}
+	return body - chunks*encOverhead, nil
+}
+
+// Encrypt returns a reader streaming the encryption of the content read from
+// r, under a new random file key.
+func (c *Cipher) Encrypt(r io.Reader) (io.Reader, error) {
+	key := make([]byte, encKeySize)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, err
+	}
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, encNonceSize)
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	header := make([]byte, 0, encHeaderSize)
+	header = append(header, encMagic...)
+	header = append(header, encVersion)
+	header = append(header, nonce...)
+	header = c.wrap.Seal(header, nonce, key, header[:len(encMagic)+1])
+
+	return &encryptReader{
+		src:   bufio.NewReader(r),
+		aead:  aead,
+		plain: make([]byte, encChunkSize),
+		buf:   header,
+	}, nil
+}
+
+// Decrypt returns a reader streaming the content decrypted from r. The reader
+// returns ErrCiphertext if the content fails authentication.
+func (c *Cipher) Decrypt(r io.Reader) (io.Reader, error) {
+	header := make([]byte, encHeaderSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, ErrCiphertext
+		}
+		return nil, err
+	}
+
+	aead, err := c.unwrapKey(header)
+	if err != nil {
+		return nil, err
+	}
+	return newDecryptReader(r, aead, 0), nil
+}
+
+// unwrapKey returns the AEAD of the file key wrapped in header.
+func (c *Cipher) unwrapKey(header []byte) (cipher.AEAD, error) {
+	prefix := header[:len(encMagic)+1]
+	if string(prefix[:len(encMagic)]) != encMagic || prefix[len(encMagic)] != encVersion {
+		return nil, ErrCiphertext
+	}
+
+	nonce := header[len(prefix) : len(prefix)+encNonceSize]
+	key, err := c.wrap.Open(nil, nonce, header[len(prefix)+encNonceSize:], prefix)
+	if err != nil {
+		return nil, ErrCiphertext
+	}
+	return newGCM(key)
+}
+
+// chunkNonce returns the nonce of the chunk with the given index.
+func chunkNonce(index uint64, last bool) []byte {
+	nonce := make([]byte, encNonceSize)
+	binary.BigEndian.PutUint64(nonce, index)
+	if last {
+		nonce[encNonceSize-1] = 1
+	}
+	return nonce
+}
+
+// encryptReader encrypts the content read from src chunk by chunk.
+type encryptReader struct {
+	src   *bufio.Reader
+	aead  cipher.AEAD
+	index uint64
+	plain []byte
+	buf   []byte // encrypted bytes not read yet
+	done  bool
+}
+
+func (r *encryptReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+		if err := r.fill(); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+// fill encrypts the next chunk into buf.
+func (r *encryptReader) fill() error {
+	n, err := io.ReadFull(r.src, r.plain)
+	last := false
+	switch {
+	case err == io.EOF || err == io.ErrUnexpectedEOF:
+		last = true
+	case err != nil:
+		return err
+	default:
+		// a full chunk is the last one if nothing follows
+		if _, err := r.src.Peek(1); err == io.EOF {
+			last = true
+		} else if err != nil {
+			return err
+		}
+	}
+
+	r.buf = r.aead.Seal(r.buf[:0], chunkNonce(r.index, last), r.plain[:n], nil)
+	r.index++
+	r.done = last
+	return nil
+}
+
+// decryptReader decrypts the chunks read from src, starting with the chunk
+// with the given index.
+type decryptReader struct {
+	src   *bufio.Reader
+	aead  cipher.AEAD
+	index uint64
+	chunk []byte
+	buf   []byte // decrypted bytes not read yet
+	skip  int    // bytes to drop from the first chunk
+	done  bool
+
+	closer io.Closer // closes src, if not nil
+}
+
+func newDecryptReader(r io.Reader, aead cipher.AEAD, index uint64) *decryptReader {
+	return &decryptReader{
+		src:   bufio.NewReader(r),
+		aead:  aead,
+		index: index,
+		chunk: make([]byte, encChunkSize+encOverhead),
+	}
+}
+
+func (r *decryptReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+		if err := r.fill(); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+// fill decrypts the next chunk into buf.
+func (r *decryptReader) fill() error {
+	n, err := io.ReadFull(r.src, r.chunk)
+	last := false
+	switch {
+	case err == io.EOF:
+		// the last chunk is missing
+		return ErrCiphertext
+	case err == io.ErrUnexpectedEOF:
+		last = true
+	case err != nil:
+		return err
+	default:
+		if _, err := r.src.Peek(1); err == io.EOF {
+			last = true
+		} else if err != nil {
+			return err
+		}
+	}
+
+	plain, err := r.aead.Open(r.chunk[:0], chunkNonce(r.index, last), r.chunk[:n], nil)
+	if err != nil {
+		return ErrCiphertext
+	}
+	if r.skip > 0 {
+		if r.skip > len(plain) {
+			return ErrCiphertext
+		}
+		plain = plain[r.skip:]
+		r.skip = 0
+	}
+
+	r.buf = plain
+	r.index++
+	r.done = last
+	return nil
+}
+
+func (r *decryptReader) Close() error {
+	if r.closer == nil {
+		return nil
+	}
+	return r.closer.Close()
+}
+
+// Upload encrypts the local file at path and stores it as name (encrypted if
+// EncryptNames is set) into folder f. See Folder.Upload.
+func (c *Cipher) Upload(ctx context.Context, f *Folder, path, name string) (*File, *http.Response, error) {
+	in, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer in.Close()
+
+	fi, err := in.Stat()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	r, err := c.Encrypt(in)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	opts := &UploadOptions{Size: EncryptedSize(fi.Size())}
+	if !c.EncryptNames {
+		opts.Filename = filepath.Base(path)
+	}
+	return f.UploadReader(ctx, r, c.nodeName(name), opts)
+}
+
+// Download decrypts the content of file f into the file pointed to by path.
+// Errors if the file at path already exists. The file at path is removed if
+// the content fails to download or to decrypt. See File.Download.
+func (c *Cipher) Download(ctx context.Context, f *File, path string) (*http.Response, error) {
+	body, resp, err := f.Open(ctx)
+	if err != nil {
+		return resp, err
+	}
+	defer body.Close()
+
+	out, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0666)
+	if err != nil {
+		return resp, err
+	}
+	defer out.Close()
+
+	r, err := c.Decrypt(body)
+	if err == nil {
+		_, err = io.Copy(out, r)
+	}
+	if err != nil {
+		out.Close()
+		os.Remove(path)
+		return resp, err
+	}
+	return resp, nil
+}
+
+// Size returns the size of the decrypted content of file f.
+func (c *Cipher) Size(ctx context.Context, f *File) (int64, error) {
+	size, err := f.NewReader(ctx).Size()
+	if err != nil {
+		return 0, err
+	}
+	return DecryptedSize(size)
+}
+
+// Open returns a reader streaming the decrypted content of file f. The caller
+// must close the reader when done. See File.Open.
+func (c *Cipher) Open(ctx context.Context, f *File) (io.ReadCloser, *http.Response, error) {
+	body, resp, err := f.Open(ctx)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	r, err := c.Decrypt(body)
+	if err != nil {
+		body.Close()
+		return nil, resp, err
+	}
+	dr := r.(*decryptReader)
+	dr.closer = body
+	return dr, resp, nil
+}
+
+// OpenRange returns a reader streaming length bytes of the decrypted content
+// of file f, starting at offset. A negative length reads until the end of the
+// file. Only the chunks covering the range are downloaded. The caller must
+// close the reader when done. See File.OpenRange.
+func (c *Cipher) OpenRange(ctx context.Context, f *File, offset, length int64) (io.ReadCloser, *http.Response, error) {
+	if offset == 0 && length < 0 {
+		return c.Open(ctx, f)
+	}
+
+	size, err := c.Size(ctx, f)
+	if err != nil {
+		return nil, nil, err
+	}
+	if offset >= size || length == 0 {
+		return ioutil.NopCloser(strings.NewReader("")), nil, nil
+	}
+
+	body, resp, err := f.OpenRange(withoutProgress(ctx), 0, int64(encHeaderSize))
+	if err != nil {
+		return nil, resp, err
+	}
+	header, err := ioutil.ReadAll(body)
+	body.Close()
+	if err != nil {
+		return nil, resp, err
+	}
+	if len(header) != encHeaderSize {
+		return nil, resp, ErrCiphertext
+	}
+	aead, err := c.unwrapKey(header)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	index := offset / encChunkSize
+	start := int64(encHeaderSize) + index*(encChunkSize+encOverhead)
+	body, resp, err = f.OpenRange(ctx, start, -1)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	dr := newDecryptReader(body, aead, uint64(index))
+	dr.skip = int(offset % encChunkSize)
+	dr.closer = body
+	if length < 0 {
+		return dr, resp, nil
+	}
+	return &limitedReadCloser{io.LimitReader(dr, length), dr}, resp, nil
+}
@@ -0,0 +1,208 @@
+// Copyright (c) 2015 Serge Gebhardt. All rights reserved.
+//
+// Use of this source code is governed by the ISC
+// license that can be found in the LICENSE file.
+
+package acd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"mime"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestCipher(t *testing.T, encryptNames bool) *Cipher {
+	key, err := GenerateKey()
+	assert.NoError(t, err)
+	c, err := NewCipher(key, encryptNames)
+	assert.NoError(t, err)
+	return c
+}
+
+// testPlaintext returns size bytes of pseudo-random content.
+func testPlaintext(size int) []byte {
+	data := make([]byte, size)
+	rand.New(rand.NewSource(int64(size))).Read(data)
+	return data
+}
+
+// encryptAll returns the encryption of plain.
+func encryptAll(t *testing.T, c *Cipher, plain []byte) []byte {
+	r, err := c.Encrypt(bytes.NewReader(plain))
+	assert.NoError(t, err)
+	data, err := ioutil.ReadAll(r)
+	assert.NoError(t, err)
+	return data
+}
+
+func TestCipher_roundTrip(t *testing.T) {
+	c := newTestCipher(t, false)
+
+	for _, size := range []int{0, 1, encChunkSize - 1, encChunkSize, encChunkSize + 1, 3 * encChunkSize} {
+		plain := testPlaintext(size)
+		data := encryptAll(t, c, plain)
+		assert.Equal(t, EncryptedSize(int64(size)), int64(len(data)))
+
+		decryptedSize, err := DecryptedSize(int64(len(data)))
+		assert.NoError(t, err)
+		assert.Equal(t, int64(size), decryptedSize)
+
+		r, err := c.Decrypt(bytes.NewReader(data))
+		assert.NoError(t, err)
+		decrypted, err := ioutil.ReadAll(r)
+		assert.NoError(t, err)
+		assert.True(t, bytes.Equal(plain, decrypted), "size %d", size)
+	}
+}
+
+func TestCipher_tampered(t *testing.T) {
+	c := newTestCipher(t, false)
+	data := encryptAll(t, c, testPlaintext(2*encChunkSize+10))
+
+	tampered := append([]byte(nil), data...)
+	tampered[encHeaderSize+encChunkSize+5] ^= 1
+	r, err := c.Decrypt(bytes.NewReader(tampered))
+	assert.NoError(t, err)
+	_, err = ioutil.ReadAll(r)
+	assert.Equal(t, ErrCiphertext, err)
+
+	// dropping the last chunk
+	truncated := data[:encHeaderSize+2*(encChunkSize+encOverhead)]
+	r, err = c.Decrypt(bytes.NewReader(truncated))
+	assert.NoError(t, err)
+	_, err = ioutil.ReadAll(r)
+	assert.Equal(t, ErrCiphertext, err)
+
+	// another master key
+	_, err = newTestCipher(t, false).Decrypt(bytes.NewReader(data))
+	assert.Equal(t, ErrCiphertext, err)
+}
+
+func TestCipher_names(t *testing.T) {
+	c := newTestCipher(t, true)
+
+	enc := c.EncryptName("my photo.jpg")
+	assert.Equal(t, enc, c.EncryptName("my photo.jpg"))
+	assert.NotEqual(t, enc, c.EncryptName("my photo.png"))
+
+	name, err := c.DecryptName(enc)
+	assert.NoError(t, err)
+	assert.Equal(t, "my photo.jpg", name)
+
+	_, err = newTestCipher(t, true).DecryptName(enc)
+	assert.Equal(t, ErrCiphertext, err)
+	_, err = c.DecryptName("not encrypted")
+	assert.Equal(t, ErrCiphertext, err)
+}
+
+func TestCipher_upload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go-acd")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	plain := testPlaintext(encChunkSize + 100)
+	path := filepath.Join(dir, "secret.txt")
+	assert.NoError(t, ioutil.WriteFile(path, plain, 0666))
+
+	r := *NewMockResponseOkString(`{ "id": "newFile", "kind": "FILE" }`)
+	mc := NewMockClient(r)
+	id := "parentId"
+	folder := &Folder{&Node{Id: &id, service: mc.Nodes}}
+
+	c := newTestCipher(t, true)
+	_, resp, err := c.Upload(context.Background(), folder, path, "secret.txt")
+	assert.NoError(t, err)
+
+	body := mockTransportOf(mc).bodies[0]
+	assert.Equal(t, int64(len(body)), resp.Request.ContentLength)
+
+	_, params, err := mime.ParseMediaType(resp.Request.Header.Get("Content-Type"))
+	assert.NoError(t, err)
+	mr := multipart.NewReader(bytes.NewReader(body), params["boundary"])
+
+	part, err := mr.NextPart()
+	assert.NoError(t, err)
+	metadata, _ := ioutil.ReadAll(part)
+	assert.Contains(t, string(metadata), c.EncryptName("secret.txt"))
+	assert.False(t, strings.Contains(string(metadata), "secret.txt"))
+
+	part, err = mr.NextPart()
+	assert.NoError(t, err)
+	assert.False(t, strings.Contains(part.FileName(), "secret"))
+	dec, err := c.Decrypt(part)
+	assert.NoError(t, err)
+	data, err := ioutil.ReadAll(dec)
+	assert.NoError(t, err)
+	assert.True(t, bytes.Equal(plain, data))
+}
+
+func TestCipher_download(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go-acd")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	c := newTestCipher(t, false)
+	plain := testPlaintext(2*encChunkSize + 7)
+	f := newTestFile(NewMockContentClient(encryptAll(t, c, plain)), nil)
+
+	path := filepath.Join(dir, "foo")
+	_, err = c.Download(context.Background(), f, path)
+	assert.NoError(t, err)
+	data, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	assert.True(t, bytes.Equal(plain, data))
+
+	// wrong key removes the local file
+	path = filepath.Join(dir, "bar")
+	_, err = newTestCipher(t, false).Download(context.Background(), f, path)
+	assert.Equal(t, ErrCiphertext, err)
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestCipher_openRange(t *testing.T) {
+	c := newTestCipher(t, false)
+	plain := testPlaintext(3*encChunkSize + 123)
+	data := encryptAll(t, c, plain)
+	size := uint64(len(data))
+	mc := NewMockContentClient(data)
+	f := newTestFile(mc, &size)
+
+	cases := []struct{ offset, length int64 }{
+		{0, 10},
+		{encChunkSize - 5, 10},
+		{2*encChunkSize + 1, -1},
+		{int64(len(plain)) - 1, 100},
+		{int64(len(plain)), -1},
+	}
+	for _, tc := range cases {
+		body, _, err := c.OpenRange(context.Background(), f, tc.offset, tc.length)
+		assert.NoError(t, err)
+		got, err := ioutil.ReadAll(body)
+		assert.NoError(t, err)
+		body.Close()
+
+		end := int64(len(plain))
+		if tc.length >= 0 && tc.offset+tc.length < end {
+			end = tc.offset + tc.length
+		}
+		assert.True(t, bytes.Equal(plain[tc.offset:end], got), "range %d+%d", tc.offset, tc.length)
+	}
+
+	// only the chunks from the offset on are fetched
+	body, resp, err := c.OpenRange(context.Background(), f, 2*encChunkSize+1, 10)
+	assert.NoError(t, err)
+	body.Close()
+	start := encHeaderSize + 2*(encChunkSize+encOverhead)
+	assert.Equal(t, fmt.Sprintf("bytes=%d-", start), resp.Request.Header.Get("Range"))
+}
@@ -0,0 +1,107 @@
+// Copyright (c) 2015 Serge Gebhardt. All rights reserved.
+//
+// Use of this source code is governed by the ISC
+// license that can be found in the LICENSE file.
+
+package acd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// ErrDuplicate is matched by DuplicateError.
+var ErrDuplicate = errors.New("Duplicate content")
+
+// DuplicateError is returned when uploading content which already exists on
+// the Amazon Cloud Drive, either detected by the server when deduplication is
+// allowed (see UploadOptions.AllowDeduplication) or by the MD5 lookup of
+// Folder.UploadDeduplicated. It matches ErrDuplicate.
+type DuplicateError struct {
+	// Name of the node being uploaded.
+	Name string
+
+	// Id of the existing node with the same content.
+	NodeId string
+
+	// Existing node.
+	Node *Node
+
+	// Response of the rejected upload, if the server detected the duplicate.
+	Response *ErrorResponse
+}
+
+func (e *DuplicateError) Error() string {
+	return fmt.Sprintf("Content of '%s' already exists as node %s", e.Name, e.NodeId)
+}
+
+// Is reports whether target is ErrDuplicate. Used by errors.Is.
+func (e *DuplicateError) Is(target error) bool {
+	return target == ErrDuplicate
+}
+
+// Unwrap returns the ErrorResponse of the rejected upload, if any.
+func (e *DuplicateError) Unwrap() error {
+	if e.Response == nil {
+		return nil
+	}
+	return e.Response
+}
+
+// IsDuplicate reports whether err is a DuplicateError.
+func IsDuplicate(err error) bool {
+	return errors.Is(err, ErrDuplicate)
+}
+
+// duplicateError returns a DuplicateError if err is a 409 caused by an
+// existing file with the content of the given MD5, otherwise err. The
+// conflicting node is looked up, as a 409 may also be a name collision.
+func (s *NodesService) duplicateError(ctx context.Context, err error, name, md5 string) error {
+	var errResp *ErrorResponse
+	if !errors.As(err, &errResp) || errResp.StatusCode != http.StatusConflict || errResp.Info.NodeId == "" {
+		return err
+	}
+
+	n, _, gerr := s.GetNode(ctx, errResp.Info.NodeId)
+	if gerr != nil || contentMD5(n) != md5 {
+		return err
+	}
+	return &DuplicateError{Name: name, NodeId: *n.Id, Node: n, Response: errResp}
+}
+
+// FindByMD5 returns the available files whose content has the given MD5.
+func (s *NodesService) FindByMD5(ctx context.Context, md5 string) ([]*Node, *http.Response, error) {
+	filter := And(Kind(KindFile), Field("status", "AVAILABLE"), MD5(md5))
+	return s.GetAllNodes(ctx, &NodeListOptions{Filters: filter.String()})
+}
+
+// UploadDeduplicated uploads the local file at path as name like Upload,
+// unless a file with the same content already exists anywhere on the Amazon
+// Cloud Drive. The content is looked up by the MD5 of the local file before
+// uploading, and the server is allowed to deduplicate the upload. If the
+// content exists, the existing file is returned along with a DuplicateError,
+// and folder f does not get a file name.
+func (f *Folder) UploadDeduplicated(ctx context.Context, path, name string) (*File, *http.Response, error) {
+	sum, err := fileMD5(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	nodes, resp, err := f.service.FindByMD5(ctx, sum)
+	if err != nil {
+		return nil, resp, err
+	}
+	if len(nodes) > 0 {
+		err := &DuplicateError{Name: name, NodeId: *nodes[0].Id, Node: nodes[0]}
+		return &File{nodes[0]}, resp, err
+	}
+
+	file, resp, err := f.uploadPath(ctx, path, name, &UploadOptions{AllowDeduplication: true})
+	var dup *DuplicateError
+	if errors.As(err, &dup) {
+		file = &File{dup.Node}
+	}
+	return file, resp, err
+}
@@ -0,0 +1,125 @@
+// Copyright (c) 2015 Serge Gebhardt. All rights reserved.
+//
+// Use of this source code is governed by the ISC
+// license that can be found in the LICENSE file.
+
+package acd
+
+import (
+	"context"
+	"crypto/md5"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFolder_uploadAllowDeduplication(t *testing.T) {
+	sum := fmt.Sprintf("%x", md5.Sum([]byte("hello")))
+	c := NewMockClientRoutes(map[string]MockResponse{
+		"POST /cdproxy/nodes":          MockResponse{Code: 409, Body: []byte(`{ "message": "Duplicate", "info": { "nodeId": "existing" } }`)},
+		"GET /drive/v1/nodes/existing": *NewMockResponseOkString(`{ "id": "existing", "name": "other", "kind": "FILE", "contentProperties": { "md5": "` + sum + `" } }`),
+	})
+	c.RetryPolicy = nil
+	id := "parentId"
+	folder := &Folder{&Node{Id: &id, service: c.Nodes}}
+
+	opts := &UploadOptions{AllowDeduplication: true}
+	_, resp, err := folder.UploadReader(context.Background(), strings.NewReader("hello"), "bar", opts)
+
+	assert.Equal(t, "", resp.Request.URL.RawQuery)
+	assert.True(t, IsDuplicate(err))
+	assert.True(t, IsConflict(err))
+	var dup *DuplicateError
+	assert.True(t, errors.As(err, &dup))
+	assert.Equal(t, "bar", dup.Name)
+	assert.Equal(t, "existing", dup.NodeId)
+	assert.Equal(t, "other", *dup.Node.Name)
+}
+
+func TestFolder_uploadSuppressDeduplication(t *testing.T) {
+	r := *NewMockResponseOkString(`{ "id": "newFile", "name": "bar", "kind": "FILE" }`)
+	c := NewMockClient(r)
+	id := "parentId"
+	folder := &Folder{&Node{Id: &id, service: c.Nodes}}
+
+	_, resp, err := folder.UploadReader(context.Background(), strings.NewReader("hello"), "bar", nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "suppress=deduplication", resp.Request.URL.RawQuery)
+}
+
+func TestFolder_uploadNameConflictIsNotDuplicate(t *testing.T) {
+	// the conflicting node has other content
+	sum := fmt.Sprintf("%x", md5.Sum([]byte("something else")))
+	c := NewMockClientRoutes(map[string]MockResponse{
+		"POST /cdproxy/nodes":       MockResponse{Code: 409, Body: []byte(`{ "message": "Name exists", "info": { "nodeId": "other" } }`)},
+		"GET /drive/v1/nodes/other": *NewMockResponseOkString(`{ "id": "other", "name": "bar", "kind": "FILE", "contentProperties": { "md5": "` + sum + `" } }`),
+	})
+	c.RetryPolicy = nil
+	id := "parentId"
+	folder := &Folder{&Node{Id: &id, service: c.Nodes}}
+
+	opts := &UploadOptions{AllowDeduplication: true}
+	_, _, err := folder.UploadReader(context.Background(), strings.NewReader("hello"), "bar", opts)
+
+	assert.True(t, IsConflict(err))
+	assert.False(t, IsDuplicate(err))
+}
+
+func TestFolder_uploadDeduplicatedExisting(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go-acd")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "a.txt")
+	assert.NoError(t, ioutil.WriteFile(path, []byte("hello"), 0666))
+
+	sum := fmt.Sprintf("%x", md5.Sum([]byte("hello")))
+	c := NewMockClientRoutes(map[string]MockResponse{
+		"GET /drive/v1/nodes": *NewMockResponseOkString(`{ "count": 1, "data": [
+			{ "id": "existing", "name": "other.txt", "kind": "FILE", "contentProperties": { "md5": "` + sum + `" } }
+		] }`),
+	})
+	id := "parentId"
+	folder := &Folder{&Node{Id: &id, service: c.Nodes}}
+
+	file, _, err := folder.UploadDeduplicated(context.Background(), path, "a.txt")
+
+	assert.True(t, IsDuplicate(err))
+	assert.Equal(t, "existing", *file.Id)
+
+	reqs := mockRouteTransportOf(c).reqs
+	assert.Equal(t, 1, len(reqs))
+	assert.Equal(t, `kind:FILE AND status:AVAILABLE AND contentProperties.md5:`+sum, reqs[0].URL.Query().Get("filters"))
+}
+
+func TestFolder_uploadDeduplicatedByServer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go-acd")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "a.txt")
+	assert.NoError(t, ioutil.WriteFile(path, []byte("hello"), 0666))
+
+	sum := fmt.Sprintf("%x", md5.Sum([]byte("hello")))
+	c := NewMockClientRoutes(map[string]MockResponse{
+		"GET /drive/v1/nodes":          *NewMockResponseOkString(`{ "count": 0, "data": [] }`),
+		"POST /cdproxy/nodes":          MockResponse{Code: 409, Body: []byte(`{ "info": { "nodeId": "existing" } }`)},
+		"GET /drive/v1/nodes/existing": *NewMockResponseOkString(`{ "id": "existing", "name": "other.txt", "kind": "FILE", "contentProperties": { "md5": "` + sum + `" } }`),
+	})
+	id := "parentId"
+	folder := &Folder{&Node{Id: &id, service: c.Nodes}}
+
+	file, _, err := folder.UploadDeduplicated(context.Background(), path, "a.txt")
+
+	var dup *DuplicateError
+	assert.True(t, errors.As(err, &dup))
+	assert.Equal(t, "existing", *dup.Node.Id)
+	assert.Equal(t, "existing", *file.Id)
+}
@@ -0,0 +1,207 @@
+// Copyright (c) 2015 Serge Gebhardt. All rights reserved.
+//
+// Use of this source code is governed by the ISC
+// license that can be found in the LICENSE file.
+
+package acd
+
+import (
+	"strings"
+	"time"
+)
+
+// Node kinds, for use with Kind.
+const (
+	KindFile   = "FILE"
+	KindFolder = "FOLDER"
+	KindAsset  = "ASSET"
+)
+
+// filterTimeFormat is the date format expected in filters.
+const filterTimeFormat = "2006-01-02T15:04:05.000Z"
+
+// Filter is a filter expression on nodes, built with the functions below and
+// used as NodeListOptions.Filters:
+//
+//	f := And(Kind(KindFile), Name("my photo.jpg"), ModifiedAfter(t))
+//	opts := &NodeListOptions{Filters: f.String()}
+//
+// Values are escaped as required by the API. The empty Filter matches all
+// nodes.
+//
+// See: https://developer.amazon.com/public/apis/experience/cloud-drive/content/nodes
+type Filter struct {
+	expr     string
+	compound bool // whether expr needs parentheses when nested
+}
+
+// String returns the filter expression.
+func (f Filter) String() string {
+	return f.expr
+}
+
+// nested returns the expression for use inside another expression.
+func (f Filter) nested() string {
+	if f.compound {
+		return "(" + f.expr + ")"
+	}
+	return f.expr
+}
+
+// Field matches nodes whose field has the given value.
+func Field(field, value string) Filter {
+	return Filter{expr: field + ":" + EscapeFilterValue(value)}
+}
+
+// Kind matches nodes of the given kind, e.g. KindFile.
+func Kind(kind string) Filter {
+	return Field("kind", kind)
+}
+
+// Name matches nodes with the given name.
+func Name(name string) Filter {
+	return Field("name", name)
+}
+
+// NamePrefix matches nodes whose name starts with prefix.
+func NamePrefix(prefix string) Filter {
+	return Prefix("name", prefix)
+}
+
+// Parent matches nodes contained in the folder with the given id.
+func Parent(id string) Filter {
+	return Field("parents", id)
+}
+
+// Label matches nodes with the given label.
+func Label(label string) Filter {
+	return Field("labels", label)
+}
+
+// ContentType matches files with the given content type, e.g. "image/jpeg".
+func ContentType(contentType string) Filter {
+	return Field("contentProperties.contentType", contentType)
+}
+
+// MD5 matches files whose content has the given MD5 (hex encoded).
+func MD5(md5 string) Filter {
+	return Field("contentProperties.md5", md5)
+}
+
+// IsRoot matches the root folder.
+func IsRoot() Filter {
+	return Filter{expr: "isRoot:true"}
+}
+
+// Prefix matches nodes whose field starts with prefix.
+func Prefix(field, prefix string) Filter {
+	return Filter{expr: field + ":" + EscapeFilterValue(prefix) + "*"}
+}
+
+// Range matches nodes whose field lies between from and to, inclusively. An
+// empty from or to leaves the range open on that side. Dates are given in the
+// form "2014-01-01T00:00:00.000Z".
+func Range(field, from, to string) Filter {
+	return Filter{expr: field + ":[" + rangeBound(from) + " TO " + rangeBound(to) + "]"}
+}
+
+// rangeBound returns v for use as a range bound, "*" if empty. Only the
+// characters ending a bound are escaped, so that dates keep the form
+// expected by the API.
+func rangeBound(v string) string {
+	if v == "" {
+		return "*"
+	}
+	return escapeChars(v, rangeSpecialChars)
+}
+
+// ModifiedAfter matches nodes modified after t.
+func ModifiedAfter(t time.Time) Filter {
+	return timeRange("modifiedDate", &t, nil)
+}
+
+// ModifiedBefore matches nodes modified before t.
+func ModifiedBefore(t time.Time) Filter {
+	return timeRange("modifiedDate", nil, &t)
+}
+
+// CreatedAfter matches nodes created after t.
+func CreatedAfter(t time.Time) Filter {
+	return timeRange("createdDate", &t, nil)
+}
+
+// CreatedBefore matches nodes created before t.
+func CreatedBefore(t time.Time) Filter {
+	return timeRange("createdDate", nil, &t)
+}
+
+// timeRange matches nodes whose date field lies strictly between from and to,
+// nil meaning open.
+func timeRange(field string, from, to *time.Time) Filter {
+	bound := func(t *time.Time) string {
+		if t == nil {
+			return rangeBound("")
+		}
+		return rangeBound(t.UTC().Format(filterTimeFormat))
+	}
+	return Filter{expr: field + ":{" + bound(from) + " TO " + bound(to) + "}"}
+}
+
+// And matches nodes matching all filters.
+func And(filters ...Filter) Filter {
+	return join(" AND ", filters)
+}
+
+// Or matches nodes matching any of the filters.
+func Or(filters ...Filter) Filter {
+	return join(" OR ", filters)
+}
+
+// Not matches nodes not matching f. Not of the empty Filter is empty.
+func Not(f Filter) Filter {
+	if f.expr == "" {
+		return f
+	}
+	return Filter{expr: "NOT " + f.nested(), compound: true}
+}
+
+// join joins the non-empty filters with op.
+func join(op string, filters []Filter) Filter {
+	var exprs []string
+	var last Filter
+	for _, f := range filters {
+		if f.expr != "" {
+			exprs = append(exprs, f.nested())
+			last = f
+		}
+	}
+
+	if len(exprs) <= 1 {
+		return last
+	}
+	return Filter{expr: strings.Join(exprs, op), compound: true}
+}
+
+// EscapeFilterValue escapes the characters with a special meaning in filter
+// expressions (including whitespace) with a backslash.
+func EscapeFilterValue(v string) string {
+	return escapeChars(v, filterSpecialChars)
+}
+
+// escapeChars escapes the characters of v contained in chars with a
+// backslash.
+func escapeChars(v, chars string) string {
+	var b strings.Builder
+	for _, r := range v {
+		if strings.ContainsRune(chars, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+const filterSpecialChars = "+-&|!(){}[]^'\"~*?:\\/ \t"
+
+// rangeSpecialChars are the characters ending a range bound.
+const rangeSpecialChars = "[]{}\\ \t"
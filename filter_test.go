@@ -0,0 +1,57 @@
+// Copyright (c) 2015 Serge Gebhardt. All rights reserved.
+//
+// Use of this source code is governed by the ISC
+// license that can be found in the LICENSE file.
+
+package acd
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilter_escape(t *testing.T) {
+	assert.Equal(t, `name:my\ photo\ \(1\).jpg`, Name("my photo (1).jpg").String())
+	assert.Equal(t, `name:say\ \"hi\"\:\ x`, Name(`say "hi": x`).String())
+	assert.Equal(t, `name:a\\b\/c\*\?`, Name(`a\b/c*?`).String())
+	assert.Equal(t, `name:foo\-bar*`, NamePrefix("foo-bar").String())
+}
+
+func TestFilter_compose(t *testing.T) {
+	f := And(
+		Kind(KindFile),
+		Or(Label("PHOTO"), ContentType("image/jpeg")),
+		Not(Parent("abc")),
+	)
+	assert.Equal(t, `kind:FILE AND (labels:PHOTO OR contentProperties.contentType:image\/jpeg) AND (NOT parents:abc)`, f.String())
+
+	assert.Equal(t, "kind:FOLDER", And(Kind(KindFolder)).String())
+	assert.Equal(t, "kind:FOLDER", And(Kind(KindFolder), Or()).String())
+	assert.Equal(t, "", And().String())
+	assert.Equal(t, "", Or().String())
+	assert.Equal(t, "", Not(And()).String())
+}
+
+func TestFilter_ranges(t *testing.T) {
+	ts := time.Date(2015, 5, 1, 12, 30, 0, 0, time.FixedZone("CEST", 2*60*60))
+	assert.Equal(t, "modifiedDate:{2015-05-01T10:30:00.000Z TO *}", ModifiedAfter(ts).String())
+	assert.Equal(t, "createdDate:{* TO 2015-05-01T10:30:00.000Z}", CreatedBefore(ts).String())
+	assert.Equal(t, "contentProperties.size:[100 TO *]", Range("contentProperties.size", "100", "").String())
+	assert.Equal(t, "modifiedDate:[2014-01-01T00:00:00.000Z TO *]", Range("modifiedDate", "2014-01-01T00:00:00.000Z", "").String())
+	assert.Equal(t, `name:[a\ b TO c\]]`, Range("name", "a b", "c]").String())
+}
+
+func TestFilter_getNodeEscapesName(t *testing.T) {
+	c := NewMockClient(*NewMockResponseOkString(`{ "count": 1, "data": [{ "id": "x", "name": "a \"b\"", "kind": "FILE" }] }`))
+	id := "root"
+	root := &Folder{&Node{Id: &id, service: c.Nodes}}
+
+	_, _, err := root.GetNode(context.Background(), `a "b"`)
+
+	assert.NoError(t, err)
+	filters := mockTransportOf(c).reqs[0].URL.Query().Get("filters")
+	assert.Equal(t, `parents:root AND name:a\ \"b\"`, filters)
+}
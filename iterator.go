@@ -0,0 +1,133 @@
+// Copyright (c) 2015 Serge Gebhardt. All rights reserved.
+//
+// Use of this source code is governed by the ISC
+// license that can be found in the LICENSE file.
+
+package acd
+
+import (
+	"context"
+	"net/http"
+)
+
+// NodeIterator iterates over a listing of nodes, fetching one page at a time
+// so that only the current page is held in memory:
+//
+//	it := c.Nodes.IterateNodes(ctx, nil)
+//	defer it.Close()
+//	for it.Next() {
+//		n := it.Node()
+//		...
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+type NodeIterator struct {
+	service *NodesService
+	ctx     context.Context
+	url     string
+	opts    NodeListOptions // own copy, StartToken tracks the current page
+
+	page      []*Node
+	i         int
+	node      *Node
+	pageToken string
+	count     uint64
+	resp      *http.Response
+	done      bool
+	err       error
+}
+
+func (s *NodesService) newNodeIterator(ctx context.Context, url string, opts *NodeListOptions) *NodeIterator {
+	it := &NodeIterator{service: s, ctx: ctx, url: url}
+	if opts != nil {
+		it.opts = *opts
+		it.opts.reachedEnd = false
+	}
+	return it
+}
+
+// Next advances to the next node, which is then available through Node,
+// fetching the next page if needed. Returns false when there are no more
+// nodes or an error occurred.
+func (it *NodeIterator) Next() bool {
+	for it.i >= len(it.page) {
+		if it.done || it.err != nil {
+			it.node = nil
+			return false
+		}
+		it.fetch()
+	}
+
+	it.node = it.page[it.i]
+	it.i++
+	return true
+}
+
+func (it *NodeIterator) fetch() {
+	it.pageToken = it.opts.StartToken
+
+	nodeList, resp, err := it.service.fetchPage(it.ctx, it.url, &it.opts)
+	it.resp = resp
+	if err != nil {
+		it.err = err
+		return
+	}
+
+	it.page = nodeList.Data
+	it.i = 0
+	if nodeList.Count != nil {
+		it.count = *nodeList.Count
+	}
+	if nodeList.NextToken != nil && *nodeList.NextToken != "" {
+		it.opts.StartToken = *nodeList.NextToken
+	} else {
+		it.opts.StartToken = ""
+		it.done = true
+	}
+}
+
+// Node returns the current node.
+func (it *NodeIterator) Node() *Node {
+	return it.node
+}
+
+// Err returns the error which stopped the iteration, if any.
+func (it *NodeIterator) Err() error {
+	return it.err
+}
+
+// Count returns the total number of nodes of the listing as reported by the
+// API, or zero before the first page was fetched.
+func (it *NodeIterator) Count() uint64 {
+	return it.count
+}
+
+// PageToken returns the token of the page the current node belongs to, empty
+// for the first page. Resuming from it (see NodesService.IterateNodes) repeats
+// the nodes of the current page already seen, but does not miss any.
+func (it *NodeIterator) PageToken() string {
+	return it.pageToken
+}
+
+// NextToken returns the token of the page following the current one, empty if
+// it is the last page.
+func (it *NodeIterator) NextToken() string {
+	if it.done {
+		return ""
+	}
+	return it.opts.StartToken
+}
+
+// Response returns the HTTP response of the last page fetched.
+func (it *NodeIterator) Response() *http.Response {
+	return it.resp
+}
+
+// Close stops the iteration. Subsequent calls to Next return false.
+func (it *NodeIterator) Close() error {
+	it.done = true
+	it.page = nil
+	it.i = 0
+	return nil
+}
@@ -0,0 +1,99 @@
+// Copyright (c) 2015 Serge Gebhardt. All rights reserved.
+//
+// Use of this source code is governed by the ISC
+// license that can be found in the LICENSE file.
+
+package acd
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newPagedMockClient() *Client {
+	return NewMockClientSequence(
+		*NewMockResponseOkString(`{ "count": 3, "nextToken": "page2", "data": [{ "id": "a" }, { "id": "b" }] }`),
+		*NewMockResponseOkString(`{ "count": 3, "data": [{ "id": "c" }] }`),
+	)
+}
+
+func TestNodeIterator_pages(t *testing.T) {
+	c := newPagedMockClient()
+	opts := &NodeListOptions{Limit: 2}
+
+	it := c.Nodes.IterateNodes(context.Background(), opts)
+	defer it.Close()
+
+	assert.True(t, it.Next())
+	assert.Equal(t, "a", *it.Node().Id)
+	assert.Equal(t, uint64(3), it.Count())
+	assert.Equal(t, "", it.PageToken())
+	assert.Equal(t, "page2", it.NextToken())
+
+	assert.True(t, it.Next())
+	assert.Equal(t, "b", *it.Node().Id)
+	assert.Equal(t, 1, len(mockTransportOf(c).reqs))
+
+	assert.True(t, it.Next())
+	assert.Equal(t, "c", *it.Node().Id)
+	assert.Equal(t, "page2", it.PageToken())
+	assert.Equal(t, "", it.NextToken())
+
+	assert.False(t, it.Next())
+	assert.NoError(t, it.Err())
+	assert.Nil(t, it.Node())
+
+	reqs := mockTransportOf(c).reqs
+	assert.Equal(t, 2, len(reqs))
+	assert.Equal(t, "https://drive.amazonaws.com/drive/v1/nodes?limit=2&startToken=page2", reqs[1].URL.String())
+
+	// options are left untouched
+	assert.Equal(t, &NodeListOptions{Limit: 2}, opts)
+}
+
+func TestNodeIterator_resume(t *testing.T) {
+	c := NewMockClient(*NewMockResponseOkString(`{ "count": 3, "data": [{ "id": "c" }] }`))
+
+	it := c.Nodes.IterateNodes(context.Background(), &NodeListOptions{StartToken: "page2"})
+	defer it.Close()
+
+	assert.True(t, it.Next())
+	assert.Equal(t, "c", *it.Node().Id)
+	assert.False(t, it.Next())
+	assert.Equal(t, "https://drive.amazonaws.com/drive/v1/nodes?startToken=page2", mockTransportOf(c).reqs[0].URL.String())
+}
+
+func TestNodeIterator_error(t *testing.T) {
+	c := NewMockClientSequence(
+		*NewMockResponseOkString(`{ "count": 3, "nextToken": "page2", "data": [{ "id": "a" }] }`),
+		MockResponse{Code: 404},
+	)
+
+	it := c.Nodes.IterateNodes(context.Background(), nil)
+	defer it.Close()
+
+	assert.True(t, it.Next())
+	assert.False(t, it.Next())
+	assert.True(t, IsNotFound(it.Err()))
+	assert.Equal(t, 404, it.Response().StatusCode)
+}
+
+func TestNodes_getAllNodesReusesOptions(t *testing.T) {
+	c := newPagedMockClient()
+	opts := &NodeListOptions{}
+
+	nodes, resp, err := c.Nodes.GetAllNodes(context.Background(), opts)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(nodes))
+	assert.NotNil(t, resp)
+
+	// the mocked listing now always returns the last page
+	nodes, _, err = c.Nodes.GetAllNodes(context.Background(), opts)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(nodes))
+	assert.Equal(t, "https://drive.amazonaws.com/drive/v1/nodes", mockTransportOf(c).reqs[2].URL.String())
+}
@@ -7,16 +7,23 @@ package acd
 
 import (
 	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"mime/multipart"
 	"net/http"
+	"net/textproto"
 	"net/url"
 	"os"
 	"path/filepath"
 	"reflect"
+	"strings"
+	"time"
 
 	"github.com/google/go-querystring/query"
 )
@@ -26,13 +33,22 @@ import (
 // See: https://developer.amazon.com/public/apis/experience/cloud-drive/content/nodes
 type NodesService struct {
 	client *Client
+
+	// Optional cache of node metadata, nil by default. See NodeCache.
+	Cache *NodeCache
 }
 
 // Gets the root folder of the Amazon Cloud Drive.
-func (s *NodesService) GetRoot() (*Folder, *http.Response, error) {
-	opts := &NodeListOptions{Filters: "kind:FOLDER AND isRoot:true"}
+func (s *NodesService) GetRoot(ctx context.Context) (*Folder, *http.Response, error) {
+	if s.Cache != nil {
+		if root, ok := s.Cache.root(s); ok {
+			return &Folder{root}, nil, nil
+		}
+	}
+
+	opts := &NodeListOptions{Filters: And(Kind(KindFolder), IsRoot()).String()}
 
-	roots, resp, err := s.GetNodes(opts)
+	roots, resp, err := s.GetNodes(ctx, opts)
 	if err != nil {
 		return nil, resp, err
 	}
@@ -44,50 +60,76 @@ func (s *NodesService) GetRoot() (*Folder, *http.Response, error) {
 	return &Folder{roots[0]}, resp, nil
 }
 
+// Gets the node by id.
+func (s *NodesService) GetNode(ctx context.Context, id string) (*Node, *http.Response, error) {
+	if s.Cache != nil {
+		if n, ok := s.Cache.get(s, id); ok {
+			return n, nil, nil
+		}
+	}
+
+	url := fmt.Sprintf("nodes/%s", id)
+	return s.sendNodeRequest(ctx, "GET", url, nil)
+}
+
 // Gets the list of all nodes.
-func (s *NodesService) GetAllNodes(opts *NodeListOptions) ([]*Node, *http.Response, error) {
-	return s.listAllNodes("nodes", opts)
+func (s *NodesService) GetAllNodes(ctx context.Context, opts *NodeListOptions) ([]*Node, *http.Response, error) {
+	return s.listAllNodes(ctx, "nodes", opts)
+}
+
+// IterateNodes returns an iterator over all nodes, fetching them page by
+// page. Set opts.StartToken to resume a previous listing. opts is not
+// modified and may be nil.
+func (s *NodesService) IterateNodes(ctx context.Context, opts *NodeListOptions) *NodeIterator {
+	return s.newNodeIterator(ctx, "nodes", opts)
 }
 
 // Gets a list of nodes, up until the limit (either default or the one set in opts).
-func (s *NodesService) GetNodes(opts *NodeListOptions) ([]*Node, *http.Response, error) {
-	return s.listNodes("nodes", opts)
+func (s *NodesService) GetNodes(ctx context.Context, opts *NodeListOptions) ([]*Node, *http.Response, error) {
+	return s.listNodes(ctx, "nodes", opts)
 }
 
-func (s *NodesService) listAllNodes(url string, opts *NodeListOptions) ([]*Node, *http.Response, error) {
-	// Need opts to maintain state (NodeListOptions.reachedEnd)
-	if opts == nil {
-		opts = &NodeListOptions{}
-	}
+func (s *NodesService) listAllNodes(ctx context.Context, url string, opts *NodeListOptions) ([]*Node, *http.Response, error) {
+	it := s.newNodeIterator(ctx, url, opts)
+	defer it.Close()
 
 	result := make([]*Node, 0, 200)
-
-	for {
-		nodes, resp, err := s.listNodes(url, opts)
-		if err != nil {
-			return result, resp, err
-		}
-		if nodes == nil {
-			break
-		}
-
-		result = append(result, nodes...)
+	for it.Next() {
+		result = append(result, it.Node())
 	}
 
-	return result, nil, nil
+	return result, it.Response(), it.Err()
 }
 
-func (s *NodesService) listNodes(url string, opts *NodeListOptions) ([]*Node, *http.Response, error) {
+func (s *NodesService) listNodes(ctx context.Context, url string, opts *NodeListOptions) ([]*Node, *http.Response, error) {
 	if opts != nil && opts.reachedEnd {
 		return nil, nil, nil
 	}
 
+	nodeList, resp, err := s.fetchPage(ctx, url, opts)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	if opts != nil {
+		if nodeList.NextToken != nil {
+			opts.StartToken = *nodeList.NextToken
+		} else {
+			opts.reachedEnd = true
+		}
+	}
+
+	return nodeList.Data, resp, nil
+}
+
+// fetchPage gets a single page of nodes.
+func (s *NodesService) fetchPage(ctx context.Context, url string, opts *NodeListOptions) (*nodeListInternal, *http.Response, error) {
 	url, err := addOptions(url, opts)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	req, err := s.client.NewMetadataRequest("GET", url, nil)
+	req, err := s.client.NewMetadataRequest(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -98,20 +140,34 @@ func (s *NodesService) listNodes(url string, opts *NodeListOptions) ([]*Node, *h
 		return nil, resp, err
 	}
 
-	if opts != nil {
-		if nodeList.NextToken != nil {
-			opts.StartToken = *nodeList.NextToken
-		} else {
-			opts.reachedEnd = true
-		}
+	for _, node := range nodeList.Data {
+		node.service = s
+	}
+	if s.Cache != nil {
+		s.Cache.Put(nodeList.Data...)
 	}
 
-	nodes := nodeList.Data
-	for _, node := range nodes {
-		node.service = s
+	return nodeList, resp, nil
+}
+
+// sendNodeRequest sends a metadata request with the (optional) JSON body and
+// decodes the node it returns.
+func (s *NodesService) sendNodeRequest(ctx context.Context, method, url string, body interface{}) (*Node, *http.Response, error) {
+	req, err := s.client.NewMetadataRequest(ctx, method, url, body)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	node := &Node{service: s}
+	resp, err := s.client.Do(req, node)
+	if err != nil {
+		return nil, resp, err
 	}
 
-	return nodes, resp, nil
+	if s.Cache != nil {
+		s.Cache.Put(node)
+	}
+	return node, resp, nil
 }
 
 type nodeListInternal struct {
@@ -124,16 +180,87 @@ type nodeListInternal struct {
 // and folders, in a parent-child relationship. A node contains only metadata
 // (e.g. folder) or it contains metadata and content (e.g. file).
 type Node struct {
-	Id                *string `json:"id"`
-	Name              *string `json:"name"`
-	Kind              *string `json:"kind"`
-	ContentProperties *struct {
-		Size *uint64 `json:"size"`
-	} `json:"contentProperties"`
+	Id                *string            `json:"id"`
+	Name              *string            `json:"name"`
+	Kind              *string            `json:"kind"`
+	Version           *uint64            `json:"version"`
+	Parents           []string           `json:"parents"`
+	Labels            []string           `json:"labels"`
+	Status            *string            `json:"status"`
+	Description       *string            `json:"description"`
+	CreatedBy         *string            `json:"createdBy"`
+	CreatedDate       *time.Time         `json:"createdDate"`
+	ModifiedDate      *time.Time         `json:"modifiedDate"`
+	IsRoot            *bool              `json:"isRoot"`
+	IsShared          *bool              `json:"isShared"`
+	Restricted        *bool              `json:"restricted"`
+	ETagResponse      *string            `json:"eTagResponse"`
+	TempLink          *string            `json:"tempLink"`
+	ContentProperties *ContentProperties `json:"contentProperties"`
 
 	service *NodesService
 }
 
+// ContentProperties represents the properties of a node's content.
+type ContentProperties struct {
+	Version     *uint64          `json:"version"`
+	Size        *uint64          `json:"size"`
+	MD5         *string          `json:"md5"`
+	ContentType *string          `json:"contentType"`
+	Extension   *string          `json:"extension"`
+	ContentDate *time.Time       `json:"contentDate"`
+	Image       *ImageProperties `json:"image"`
+	Video       *VideoProperties `json:"video"`
+}
+
+// ImageProperties represents the properties (mostly EXIF) of an image.
+type ImageProperties struct {
+	Make              *string    `json:"make"`
+	Model             *string    `json:"model"`
+	ExposureTime      *string    `json:"exposureTime"`
+	DateTimeOriginal  *time.Time `json:"dateTimeOriginal"`
+	Flash             *string    `json:"flash"`
+	FocalLength       *string    `json:"focalLength"`
+	DateTime          *time.Time `json:"dateTime"`
+	DateTimeDigitized *time.Time `json:"dateTimeDigitized"`
+	Software          *string    `json:"software"`
+	Orientation       *string    `json:"orientation"`
+	ColorSpace        *string    `json:"colorSpace"`
+	MeteringMode      *string    `json:"meteringMode"`
+	ExposureProgram   *string    `json:"exposureProgram"`
+	ExposureMode      *string    `json:"exposureMode"`
+	WhiteBalance      *string    `json:"whiteBalance"`
+	SensingMethod     *string    `json:"sensingMethod"`
+	XResolution       *string    `json:"xResolution"`
+	YResolution       *string    `json:"yResolution"`
+	ResolutionUnit    *string    `json:"resolutionUnit"`
+	ApertureValue     *string    `json:"apertureValue"`
+	ISO               *string    `json:"iso"`
+	CaptureDate       *time.Time `json:"captureDate"`
+	Height            *uint64    `json:"height"`
+	Width             *uint64    `json:"width"`
+}
+
+// VideoProperties represents the properties of a video.
+type VideoProperties struct {
+	Make               *string    `json:"make"`
+	Model              *string    `json:"model"`
+	CreationDate       *time.Time `json:"creationDate"`
+	Height             *uint64    `json:"height"`
+	Width              *uint64    `json:"width"`
+	Duration           *float64   `json:"duration"`
+	Bitrate            *float64   `json:"bitrate"`
+	VideoCodec         *string    `json:"videoCodec"`
+	VideoFrameRate     *float64   `json:"videoFrameRate"`
+	AudioCodec         *string    `json:"audioCodec"`
+	AudioSampleRate    *float64   `json:"audioSampleRate"`
+	AudioChannels      *uint64    `json:"audioChannels"`
+	AudioChannelLayout *string    `json:"audioChannelLayout"`
+	Rotate             *int       `json:"rotate"`
+	Encoder            *string    `json:"encoder"`
+	Title              *string    `json:"title"`
+}
+
 // IsFile returns whether the node represents a file.
 func (n *Node) IsFile() bool {
 	return n.Kind != nil && *n.Kind == "FILE"
@@ -158,9 +285,9 @@ func (n *Node) Typed() interface{} {
 }
 
 // GetMetadata return a pretty-printed JSON string of the node's metadata
-func (n *Node) GetMetadata() (string, error) {
+func (n *Node) GetMetadata(ctx context.Context) (string, error) {
 	url := fmt.Sprintf("nodes/%s?tempLink=true", *n.Id)
-	req, err := n.service.client.NewMetadataRequest("GET", url, nil)
+	req, err := n.service.client.NewMetadataRequest(ctx, "GET", url, nil)
 	if err != nil {
 		return "", err
 	}
@@ -180,6 +307,26 @@ func (n *Node) GetMetadata() (string, error) {
 	return md.String(), nil
 }
 
+// Rename changes the name of node n and returns the updated node. If a sibling
+// with the new name already exists, the returned error satisfies IsConflict.
+func (n *Node) Rename(ctx context.Context, newName string) (*Node, *http.Response, error) {
+	url := fmt.Sprintf("nodes/%s", *n.Id)
+	body := map[string]string{"name": newName}
+	return n.service.sendNodeRequest(ctx, "PATCH", url, body)
+}
+
+// Move moves node n from folder from to folder to and returns the updated node.
+// Other parents of n are kept. If the destination already contains a node
+// with the same name, the returned error satisfies IsConflict.
+func (n *Node) Move(ctx context.Context, from, to *Folder) (*Node, *http.Response, error) {
+	url := fmt.Sprintf("nodes/%s/children", *to.Id)
+	body := map[string]string{
+		"fromParent": *from.Id,
+		"childId":    *n.Id,
+	}
+	return n.service.sendNodeRequest(ctx, "POST", url, body)
+}
+
 // File represents a file on the Amazon Cloud Drive.
 type File struct {
 	*Node
@@ -187,22 +334,229 @@ type File struct {
 
 // Download fetches the content of file f and stores it into the file pointed
 // to by path. Errors if the file at path already exists. Does not create the
-// intermediate directories in path.
-func (f *File) Download(path string) (*http.Response, error) {
+// intermediate directories in path. The file at path is removed if the download
+// fails. If the MD5 of the downloaded content does not match the node's MD5, a
+// ChecksumMismatchError is returned.
+func (f *File) Download(ctx context.Context, path string) (*http.Response, error) {
+	out, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0666)
+	if err != nil {
+		return nil, err
+	}
+
+	body, resp, err := f.OpenRange(ctx, 0, -1)
+	if err != nil {
+		out.Close()
+		os.Remove(path)
+		return resp, err
+	}
+	defer body.Close()
+
+	hash := md5.New()
+	_, err = io.Copy(io.MultiWriter(out, hash), body)
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = checkMD5(f.Node, hash, path)
+	}
+	if err != nil {
+		os.Remove(path)
+		return resp, err
+	}
+	return resp, nil
+}
+
+// Overwrite replaces the content of file f with the content read from r,
+// keeping the node id. opts may be nil. Returns the updated file, with its new
+// version and MD5.
+func (f *File) Overwrite(ctx context.Context, r io.Reader, opts *UploadOptions) (*File, *http.Response, error) {
+	if opts == nil {
+		opts = &UploadOptions{}
+	}
+
 	url := fmt.Sprintf("nodes/%s/content", *f.Id)
-	req, err := f.service.client.NewContentRequest("GET", url, nil)
+	name := ""
+	if f.Name != nil {
+		name = *f.Name
+	}
+	return f.service.uploadContent(ctx, "PUT", url, nil, r, name, opts)
+}
+
+// OverwriteFromPath replaces the content of file f with the content of the
+// local file at path. See Overwrite.
+func (f *File) OverwriteFromPath(ctx context.Context, path string) (*File, *http.Response, error) {
+	in, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer in.Close()
+
+	fi, err := in.Stat()
 	if err != nil {
+		return nil, nil, err
+	}
+
+	opts := &UploadOptions{
+		Filename: filepath.Base(path),
+		Size:     fi.Size(),
+	}
+	return f.Overwrite(ctx, in, opts)
+}
+
+// partialSuffix is appended to the target path of DownloadResume while the
+// download is in progress.
+const partialSuffix = ".acdpart"
+
+// DownloadResume fetches the content of file f like Download, but keeps the
+// content downloaded so far in path + ".acdpart" and continues from there when
+// called again after a failure. Once complete, the content is checked against
+// the node's MD5 (if known) and the partial file is renamed to path. Errors if
+// the file at path already exists.
+func (f *File) DownloadResume(ctx context.Context, path string) (*http.Response, error) {
+	if _, err := os.Lstat(path); err == nil {
+		return nil, errors.New(fmt.Sprintf("File '%s' already exists", path))
+	} else if !os.IsNotExist(err) {
 		return nil, err
 	}
 
-	out, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0666)
+	partialPath := path + partialSuffix
+	out, err := os.OpenFile(partialPath, os.O_RDWR|os.O_CREATE, 0666)
 	if err != nil {
 		return nil, err
 	}
 	defer out.Close()
 
-	resp, err := f.service.client.Do(req, out)
-	return resp, err
+	// hash what we already have, the remainder is hashed while downloading
+	hash := md5.New()
+	offset, err := io.Copy(hash, out)
+	if err != nil {
+		return nil, err
+	}
+
+	size := int64(-1)
+	if f.ContentProperties != nil && f.ContentProperties.Size != nil {
+		size = int64(*f.ContentProperties.Size)
+	}
+	if size >= 0 && offset > size {
+		// not a prefix of the content, start over
+		if err := out.Truncate(0); err != nil {
+			return nil, err
+		}
+		if _, err := out.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+		hash.Reset()
+		offset = 0
+	}
+
+	var resp *http.Response
+	if size < 0 || offset < size {
+		var body io.ReadCloser
+		body, resp, err = f.OpenRange(ctx, offset, -1)
+		if err != nil {
+			return resp, err
+		}
+		defer body.Close()
+
+		if _, err := io.Copy(io.MultiWriter(out, hash), body); err != nil {
+			return resp, err
+		}
+	}
+
+	if err := checkMD5(f.Node, hash, path); err != nil {
+		out.Close()
+		os.Remove(partialPath)
+		return resp, err
+	}
+
+	if err := out.Close(); err != nil {
+		return resp, err
+	}
+	return resp, os.Rename(partialPath, path)
+}
+
+// Open returns a reader streaming the content of file f. The caller must close
+// the reader when done. If the node's MD5 is known, the content is verified
+// against it and the reader returns a ChecksumMismatchError instead of io.EOF
+// on mismatch.
+func (f *File) Open(ctx context.Context) (io.ReadCloser, *http.Response, error) {
+	body, resp, err := f.OpenRange(ctx, 0, -1)
+	if err != nil || contentMD5(f.Node) == "" {
+		return body, resp, err
+	}
+
+	name := ""
+	if f.Name != nil {
+		name = *f.Name
+	}
+	return newVerifyingReadCloser(body, f.Node, name), resp, nil
+}
+
+// OpenRange returns a reader streaming length bytes of the content of file f,
+// starting at offset. A negative length reads until the end of the file, a
+// zero length returns an empty reader without a request. The caller must close
+// the reader when done.
+func (f *File) OpenRange(ctx context.Context, offset, length int64) (io.ReadCloser, *http.Response, error) {
+	if length == 0 {
+		return ioutil.NopCloser(strings.NewReader("")), nil, nil
+	}
+
+	url := fmt.Sprintf("nodes/%s/content", *f.Id)
+	req, err := f.service.client.NewContentRequest(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if offset > 0 || length >= 0 {
+		r := fmt.Sprintf("bytes=%d-", offset)
+		if length >= 0 {
+			r += fmt.Sprint(offset + length - 1)
+		}
+		req.Header.Set("Range", r)
+	}
+
+	resp, err := f.service.client.do(req)
+	if err != nil {
+		if resp != nil {
+			resp.Body.Close()
+		}
+		return nil, resp, err
+	}
+
+	body := resp.Body
+	if resp.StatusCode != http.StatusPartialContent && offset > 0 {
+		// Range was ignored, skip to offset ourselves
+		if _, err := io.CopyN(ioutil.Discard, body, offset); err != nil {
+			body.Close()
+			return nil, resp, err
+		}
+	}
+	if length >= 0 {
+		body = &limitedReadCloser{io.LimitReader(body, length), body}
+	}
+
+	total := length
+	if total < 0 && resp.ContentLength >= 0 {
+		total = resp.ContentLength
+		if resp.StatusCode != http.StatusPartialContent {
+			total -= offset
+		}
+	}
+	name := ""
+	if f.Name != nil {
+		name = *f.Name
+	}
+	if tracker := newProgressTracker(ctx, total, name, *f.Id); tracker != nil {
+		body = &progressReadCloser{body, tracker}
+	}
+
+	return body, resp, nil
+}
+
+// limitedReadCloser limits the bytes read from a ReadCloser.
+type limitedReadCloser struct {
+	io.Reader
+	io.Closer
 }
 
 // Folder represents a folder on the Amazon Cloud Drive.
@@ -211,20 +565,39 @@ type Folder struct {
 }
 
 // Gets the list of all children.
-func (f *Folder) GetAllChildren(opts *NodeListOptions) ([]*Node, *http.Response, error) {
+func (f *Folder) GetAllChildren(ctx context.Context, opts *NodeListOptions) ([]*Node, *http.Response, error) {
+	cache := f.service.Cache
+	cacheable := cache != nil && (opts == nil || opts.Filters == "" && opts.Sort == "" && opts.StartToken == "")
+	if cacheable {
+		if nodes, ok := cache.listChildren(f.service, *f.Id); ok {
+			return nodes, nil, nil
+		}
+	}
+
+	url := fmt.Sprintf("nodes/%s/children", *f.Id)
+	nodes, resp, err := f.service.listAllNodes(ctx, url, opts)
+	if err == nil && cacheable {
+		cache.putChildren(*f.Id, nodes)
+	}
+	return nodes, resp, err
+}
+
+// IterateChildren returns an iterator over all children, fetching them page
+// by page. See NodesService.IterateNodes.
+func (f *Folder) IterateChildren(ctx context.Context, opts *NodeListOptions) *NodeIterator {
 	url := fmt.Sprintf("nodes/%s/children", *f.Id)
-	return f.service.listAllNodes(url, opts)
+	return f.service.newNodeIterator(ctx, url, opts)
 }
 
 // Gets a list of children, up until the limit (either default or the one set in opts).
-func (f *Folder) GetChildren(opts *NodeListOptions) ([]*Node, *http.Response, error) {
+func (f *Folder) GetChildren(ctx context.Context, opts *NodeListOptions) ([]*Node, *http.Response, error) {
 	url := fmt.Sprintf("nodes/%s/children", *f.Id)
-	return f.service.listNodes(url, opts)
+	return f.service.listNodes(ctx, url, opts)
 }
 
 // Gets the subfolder by name. It is an error if not exactly one subfolder is found.
-func (f *Folder) GetFolder(name string) (*Folder, *http.Response, error) {
-	n, resp, err := f.GetNode(name)
+func (f *Folder) GetFolder(ctx context.Context, name string) (*Folder, *http.Response, error) {
+	n, resp, err := f.GetNode(ctx, name)
 	if err != nil {
 		return nil, resp, err
 	}
@@ -239,8 +612,8 @@ func (f *Folder) GetFolder(name string) (*Folder, *http.Response, error) {
 }
 
 // Gets the file by name. It is an error if not exactly one file is found.
-func (f *Folder) GetFile(name string) (*File, *http.Response, error) {
-	n, resp, err := f.GetNode(name)
+func (f *Folder) GetFile(ctx context.Context, name string) (*File, *http.Response, error) {
+	n, resp, err := f.GetNode(ctx, name)
 	if err != nil {
 		return nil, resp, err
 	}
@@ -255,17 +628,24 @@ func (f *Folder) GetFile(name string) (*File, *http.Response, error) {
 }
 
 // Gets the node by name. It is an error if not exactly one node is found.
-func (f *Folder) GetNode(name string) (*Node, *http.Response, error) {
-	filter := fmt.Sprintf("parents:\"%v\" AND name:\"%s\"", *f.Id, name)
-	opts := &NodeListOptions{Filters: filter}
+func (f *Folder) GetNode(ctx context.Context, name string) (*Node, *http.Response, error) {
+	if f.service.Cache != nil {
+		// a miss is only a hint, another client may have created the node
+		// since the cache was synced
+		if n, ok := f.service.Cache.child(f.service, *f.Id, name); ok {
+			return n, nil, nil
+		}
+	}
 
-	nodes, resp, err := f.service.GetNodes(opts)
+	opts := &NodeListOptions{Filters: And(Parent(*f.Id), Name(name)).String()}
+
+	nodes, resp, err := f.service.GetNodes(ctx, opts)
 	if err != nil {
 		return nil, resp, err
 	}
 
 	if len(nodes) < 1 {
-		err := errors.New(fmt.Sprintf("No node '%s' found", name))
+		err := &NodeNotFoundError{Name: name}
 		return nil, resp, err
 	}
 	if len(nodes) > 1 {
@@ -279,7 +659,7 @@ func (f *Folder) GetNode(name string) (*Node, *http.Response, error) {
 // WalkNodes walks the given node hierarchy, getting each node along the way, and returns
 // the deepest node. If an error occurs, returns the furthest successful node and the list
 // of HTTP responses.
-func (f *Folder) WalkNodes(names ...string) (*Node, []*http.Response, error) {
+func (f *Folder) WalkNodes(ctx context.Context, names ...string) (*Node, []*http.Response, error) {
 	resps := make([]*http.Response, 0, len(names))
 
 	if len(names) == 0 {
@@ -289,7 +669,7 @@ func (f *Folder) WalkNodes(names ...string) (*Node, []*http.Response, error) {
 	// process each node except the last one
 	fp := f
 	for _, name := range names[:len(names)-1] {
-		fn, resp, err := fp.GetFolder(name)
+		fn, resp, err := fp.GetFolder(ctx, name)
 		resps = append(resps, resp)
 		if err != nil {
 			return fp.Node, resps, err
@@ -299,7 +679,7 @@ func (f *Folder) WalkNodes(names ...string) (*Node, []*http.Response, error) {
 	}
 
 	// process the last node
-	nl, resp, err := fp.GetNode(names[len(names)-1])
+	nl, resp, err := fp.GetNode(ctx, names[len(names)-1])
 	resps = append(resps, resp)
 	if err != nil {
 		return fp.Node, resps, err
@@ -308,67 +688,294 @@ func (f *Folder) WalkNodes(names ...string) (*Node, []*http.Response, error) {
 	return nl, resps, nil
 }
 
+// CreateFolder creates the subfolder name. Errors if a node with that name
+// already exists.
+func (f *Folder) CreateFolder(ctx context.Context, name string) (*Folder, *http.Response, error) {
+	metadata := &newNodeMetadata{
+		Name:    name,
+		Kind:    "FOLDER",
+		Parents: []string{*f.Id},
+	}
+
+	req, err := f.service.client.NewMetadataRequest(ctx, "POST", "nodes", metadata)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	folder := &Folder{&Node{service: f.service}}
+	resp, err := f.service.client.Do(req, folder)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	if f.service.Cache != nil {
+		f.service.Cache.Put(folder.Node)
+	}
+	return folder, resp, nil
+}
+
+// MkdirAll walks the given folder hierarchy like WalkNodes, creating the
+// folders which do not exist yet, and returns the deepest folder. It is not an
+// error if another client creates one of the folders concurrently. If an error
+// occurs, returns the furthest successful folder and the list of HTTP
+// responses.
+func (f *Folder) MkdirAll(ctx context.Context, names ...string) (*Folder, []*http.Response, error) {
+	resps := make([]*http.Response, 0, len(names))
+
+	fp := f
+	for _, name := range names {
+		fn, resp, err := fp.GetFolder(ctx, name)
+		resps = append(resps, resp)
+		if IsNotFound(err) {
+			fn, resp, err = fp.CreateFolder(ctx, name)
+			resps = append(resps, resp)
+			if IsConflict(err) {
+				// lost the race against another client, use its folder
+				fn, resp, err = fp.GetFolder(ctx, name)
+				resps = append(resps, resp)
+			}
+		}
+		if err != nil {
+			return fp, resps, err
+		}
+
+		fp = fn
+	}
+
+	return fp, resps, nil
+}
+
 // Upload stores the content of file at path as name on the Amazon Cloud Drive.
 // Errors if the file already exists on the drive.
-func (f *Folder) Upload(path, name string) (*File, *http.Response, error) {
+func (f *Folder) Upload(ctx context.Context, path, name string) (*File, *http.Response, error) {
+	return f.uploadPath(ctx, path, name, &UploadOptions{})
+}
+
+// uploadPath uploads the content of file at path as name with the given
+// options. The size and file name are taken from the local file.
+func (f *Folder) uploadPath(ctx context.Context, path, name string, opts *UploadOptions) (*File, *http.Response, error) {
 	in, err := os.Open(path)
 	if err != nil {
 		return nil, nil, err
 	}
+	defer in.Close()
+
+	fi, err := in.Stat()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	opts.Filename = filepath.Base(path)
+	opts.Size = fi.Size()
+	return f.UploadReader(ctx, in, name, opts)
+}
+
+// UploadReader stores the content read from r as name on the Amazon Cloud
+// Drive. The content is streamed, r is read until EOF. opts may be nil. Errors
+// if the file already exists on the drive.
+func (f *Folder) UploadReader(ctx context.Context, r io.Reader, name string, opts *UploadOptions) (*File, *http.Response, error) {
+	if opts == nil {
+		opts = &UploadOptions{}
+	}
+
+	metadata, err := json.Marshal(&newNodeMetadata{
+		Name:    name,
+		Kind:    "FILE",
+		Parents: []string{*f.Id},
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	url := "nodes?suppress=deduplication"
+	if opts.AllowDeduplication {
+		url = "nodes"
+	}
+	return f.service.uploadContent(ctx, "POST", url, metadata, r, name, opts)
+}
+
+// uploadContent sends the content read from r (and the metadata, if not nil)
+// as multipart body to url, and returns the resulting file. The body is
+// streamed from a goroutine through a pipe.
+func (s *NodesService) uploadContent(ctx context.Context, method, url string, metadata []byte,
+	r io.Reader, name string, opts *UploadOptions) (*File, *http.Response, error) {
+
+	filename := opts.Filename
+	if filename == "" {
+		filename = name
+	}
+	contentType := opts.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
 
 	bodyReader, bodyWriter := io.Pipe()
-	writer := multipart.NewWriter(bodyWriter)
-	contentType := writer.FormDataContentType()
+	boundary := multipart.NewWriter(nil).Boundary()
+
+	contentLength := int64(-1)
+	if opts.Size > 0 {
+		// compute the exact length by writing the multipart framing alone
+		counter := &countingWriter{}
+		cw := multipart.NewWriter(counter)
+		cw.SetBoundary(boundary)
+		if err := writeUpload(cw, metadata, filename, contentType, &bytes.Buffer{}); err == nil {
+			contentLength = counter.n + opts.Size
+		}
+	}
+
+	var w io.Writer = bodyWriter
+	tracker := newProgressTracker(ctx, contentLength, name, "")
+	if tracker != nil {
+		defer tracker.finish()
+		w = &progressWriter{w, tracker}
+	}
+	writer := multipart.NewWriter(w)
+	writer.SetBoundary(boundary)
 
+	hash := md5.New()
 	errChan := make(chan error, 1)
 	go func() {
 		defer bodyWriter.Close()
-		defer in.Close()
-
-		err = writer.WriteField("metadata", `{"name":"`+name+`","kind":"FILE","parents":["`+*f.Id+`"]}`)
-		if err != nil {
-			errChan <- err
-			return
-		}
-
-		part, err := writer.CreateFormFile("content", filepath.Base(path))
-		if err != nil {
-			errChan <- err
-			return
-		}
-		if _, err := io.Copy(part, in); err != nil {
-			errChan <- err
-			return
-		}
-		errChan <- writer.Close()
+		errChan <- writeUpload(writer, metadata, filename, contentType, io.TeeReader(r, hash))
 	}()
 
-	req, err := f.service.client.NewContentRequest("POST", "nodes?suppress=deduplication", bodyReader)
+	req, err := s.client.NewContentRequest(ctx, method, url, bodyReader)
 	if err != nil {
+		// unblock the writing goroutine
+		bodyReader.CloseWithError(err)
+		<-errChan
 		return nil, nil, err
 	}
 
-	req.Header.Add("Content-Type", contentType)
+	req.Header.Add("Content-Type", writer.FormDataContentType())
+	if contentLength >= 0 {
+		req.ContentLength = contentLength
+	}
 
-	file := &File{&Node{service: f.service}}
-	resp, err := f.service.client.Do(req, file)
+	file := &File{&Node{service: s}}
+	resp, err := s.client.Do(req, file)
 	if err != nil {
-		return nil, nil, err
+		// the request may have failed before consuming the whole body,
+		// unblock the writing goroutine
+		bodyReader.CloseWithError(err)
+		werr := <-errChan
+		if opts.AllowDeduplication && werr == nil {
+			// the whole content was sent, its MD5 is complete
+			err = s.duplicateError(ctx, err, name, hex.EncodeToString(hash.Sum(nil)))
+		}
+		return nil, resp, err
 	}
 
 	err = <-errChan
 	if err != nil {
-		return nil, nil, err
+		return nil, resp, err
+	}
+
+	if s.Cache != nil {
+		s.Cache.Put(file.Node)
+	}
+	if tracker != nil && file.Id != nil {
+		tracker.setNodeId(*file.Id)
 	}
 
-	return file, resp, err
+	if err := checkMD5(file.Node, hash, name); err != nil {
+		if opts.TrashOnMismatch {
+			if _, resp, terr := file.Trash(ctx); terr != nil {
+				return file, resp, terr
+			}
+		}
+		return file, resp, err
+	}
+	return file, resp, nil
+}
+
+// UploadOptions holds the optional parameters of an upload.
+type UploadOptions struct {
+	// Size of the content in bytes, if known in advance. Allows sending the
+	// request with a Content-Length instead of chunked. Zero means unknown.
+	Size int64
+
+	// MIME type of the content. Defaults to application/octet-stream.
+	ContentType string
+
+	// File name sent along with the content. Defaults to the node name.
+	Filename string
+
+	// Let the server deduplicate the content instead of suppressing it. If
+	// the content already exists, the upload fails with a DuplicateError
+	// holding the existing node, which is looked up to confirm that its MD5
+	// matches the content sent. Other conflicts are returned as is.
+	AllowDeduplication bool
+
+	// Move the uploaded node to the trash if its MD5 does not match the
+	// content sent. The ChecksumMismatchError is returned either way.
+	TrashOnMismatch bool
+}
+
+// newNodeMetadata is the metadata of a node being created.
+type newNodeMetadata struct {
+	Name    string   `json:"name"`
+	Kind    string   `json:"kind"`
+	Parents []string `json:"parents"`
+}
+
+// writeUpload writes the multipart body of an upload to w. The metadata part
+// is omitted if metadata is nil.
+func writeUpload(w *multipart.Writer, metadata []byte, filename, contentType string, content io.Reader) error {
+	if metadata != nil {
+		err := w.WriteField("metadata", string(metadata))
+		if err != nil {
+			return err
+		}
+	}
+
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Disposition",
+		fmt.Sprintf(`form-data; name="content"; filename="%s"`, quoteEscaper.Replace(filename)))
+	h.Set("Content-Type", contentType)
+	part, err := w.CreatePart(h)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(part, content); err != nil {
+		return err
+	}
+
+	return w.Close()
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+// countingWriter discards everything written to it, counting the bytes.
+type countingWriter struct {
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
+
+// NodeNotFoundError is returned when looking up a node by name which does not
+// exist. It matches ErrNotFound.
+type NodeNotFoundError struct {
+	Name string
+}
+
+func (e *NodeNotFoundError) Error() string {
+	return fmt.Sprintf("No node '%s' found", e.Name)
+}
+
+// Is reports whether target is ErrNotFound. Used by errors.Is.
+func (e *NodeNotFoundError) Is(target error) bool {
+	return target == ErrNotFound
 }
 
 // NodeListOptions holds the options when getting a list of nodes, such as the filter,
 // sorting and pagination.
 type NodeListOptions struct {
 	Limit   uint   `url:"limit,omitempty"`
-	Filters string `url:"filters,omitempty"`
+	Filters string `url:"filters,omitempty"` // build with Filter
 	Sort    string `url:"sort,omitempty"`
 
 	// Token where to start for next page (internal)
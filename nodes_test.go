@@ -6,6 +6,12 @@
 package acd
 
 import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -40,11 +46,17 @@ func TestNode_getRoot(t *testing.T) {
 `)
 	c := NewMockClient(r)
 
-	root, _, err := c.Nodes.GetRoot()
+	root, _, err := c.Nodes.GetRoot(context.Background())
 
 	assert.NoError(t, err)
 	assert.Equal(t, "3ohaT2SSQWOecmP0GSWv6g", *root.Id)
 	assert.Nil(t, root.Name)
+	assert.True(t, *root.IsRoot)
+	assert.Equal(t, uint64(156), *root.Version)
+	assert.Equal(t, "2014-04-08 20:58:58.011 +0000 UTC", root.CreatedDate.String())
+	assert.Equal(t, "2015-05-03 16:12:35.394 +0000 UTC", root.ModifiedDate.String())
+	assert.Equal(t, 0, len(root.Parents))
+	assert.Equal(t, "AVAILABLE", *root.Status)
 }
 
 func TestNode_getNodes(t *testing.T) {
@@ -126,7 +138,7 @@ func TestNode_getNodes(t *testing.T) {
 	c := NewMockClient(r)
 	opts := &NodeListOptions{}
 
-	nodes, _, err := c.Nodes.GetNodes(opts)
+	nodes, _, err := c.Nodes.GetNodes(context.Background(), opts)
 
 	assert.NoError(t, err)
 	assert.Equal(t, "kgkbpodpt6", opts.StartToken)
@@ -134,7 +146,230 @@ func TestNode_getNodes(t *testing.T) {
 
 	assert.Equal(t, "eRkZ6YMuX5W3VqV3Ia7_lf", *nodes[0].Id)
 	assert.Equal(t, "fooNew.jpg", *nodes[0].Name)
+	assert.Equal(t, []string{"PHOTO"}, nodes[0].Labels)
+	assert.Equal(t, []string{"foo1", "123"}, nodes[0].Parents)
+	assert.Equal(t, "My Awesome Photo", *nodes[0].Description)
+	assert.Equal(t, "ApplicationId1", *nodes[0].CreatedBy)
+	assert.Equal(t, "eodh1-sfNbMI", *nodes[0].ETagResponse)
+	assert.False(t, *nodes[0].Restricted)
+	assert.Equal(t, "SAMSUNG", *nodes[0].ContentProperties.Image.Make)
+	assert.Equal(t, "1/1780", *nodes[0].ContentProperties.Image.ExposureTime)
+	assert.Equal(t, "2012-08-25 14:23:24 +0000 UTC", nodes[0].ContentProperties.Image.DateTimeOriginal.String())
 
 	assert.Equal(t, "fooo1", *nodes[1].Id)
 	assert.Equal(t, "foo.zip", *nodes[1].Name)
 }
+
+func TestFolder_uploadReader(t *testing.T) {
+	r := *NewMockResponseOkString(`{ "id": "newFile", "name": "bar \"quoted\".txt", "kind": "FILE" }`)
+	c := NewMockClient(r)
+	id := "parentId"
+	folder := &Folder{&Node{Id: &id, service: c.Nodes}}
+
+	content := "some generated content"
+	opts := &UploadOptions{Size: int64(len(content)), ContentType: "text/plain"}
+	file, resp, err := folder.UploadReader(context.Background(), strings.NewReader(content), `bar "quoted".txt`, opts)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "newFile", *file.Id)
+
+	body := mockTransportOf(c).bodies[0]
+	assert.Equal(t, int64(len(body)), resp.Request.ContentLength)
+
+	_, params, err := mime.ParseMediaType(resp.Request.Header.Get("Content-Type"))
+	assert.NoError(t, err)
+	mr := multipart.NewReader(bytes.NewReader(body), params["boundary"])
+
+	part, err := mr.NextPart()
+	assert.NoError(t, err)
+	assert.Equal(t, "metadata", part.FormName())
+	metadata, _ := ioutil.ReadAll(part)
+	assert.Equal(t, `{"name":"bar \"quoted\".txt","kind":"FILE","parents":["parentId"]}`, string(metadata))
+
+	part, err = mr.NextPart()
+	assert.NoError(t, err)
+	assert.Equal(t, "content", part.FormName())
+	assert.Equal(t, "text/plain", part.Header.Get("Content-Type"))
+	data, _ := ioutil.ReadAll(part)
+	assert.Equal(t, content, string(data))
+}
+
+func TestFile_overwrite(t *testing.T) {
+	r := *NewMockResponseOkString(`
+{
+	"id": "fooo1",
+	"name": "foo.txt",
+	"kind": "FILE",
+	"version": 3,
+	"contentProperties": {
+		"size": 11,
+		"md5": "5eb63bbbe01eeed093cb22bb8f5acdc3"
+	}
+}
+`)
+	c := NewMockClient(r)
+	id, name := "fooo1", "foo.txt"
+	file := &File{&Node{Id: &id, Name: &name, service: c.Nodes}}
+
+	updated, resp, err := file.Overwrite(context.Background(), strings.NewReader("hello world"), nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "PUT", resp.Request.Method)
+	assert.Equal(t, "https://content-na.drive.amazonaws.com/cdproxy/nodes/fooo1/content", resp.Request.URL.String())
+	assert.Equal(t, "fooo1", *updated.Id)
+	assert.Equal(t, uint64(3), *updated.Version)
+	assert.Equal(t, "5eb63bbbe01eeed093cb22bb8f5acdc3", *updated.ContentProperties.MD5)
+
+	body := string(mockTransportOf(c).bodies[0])
+	assert.False(t, strings.Contains(body, `name="metadata"`))
+	assert.True(t, strings.Contains(body, "hello world"))
+}
+
+func TestNode_contentProperties(t *testing.T) {
+	r := *NewMockResponseOkString(`
+{
+	"count":1,
+	"data":[
+		{
+			"id":"vid1",
+			"name":"clip.mp4",
+			"kind":"FILE",
+			"version":2,
+			"tempLink":"https://content-na.drive.amazonaws.com/cdproxy/templink/abc",
+			"isShared":true,
+			"contentProperties":{
+				"version":2,
+				"size":1048576,
+				"md5":"6df23dc03f9b54cc38a0fc1483df6e21",
+				"contentType":"video/mp4",
+				"extension":"mp4",
+				"contentDate":"2015-04-01T10:00:00.000Z",
+				"video":{
+					"height":1080,
+					"width":1920,
+					"duration":12.5,
+					"videoCodec":"h264",
+					"audioCodec":"aac",
+					"audioChannels":2,
+					"rotate":90
+				}
+			}
+		}
+	]
+}
+`)
+	c := NewMockClient(r)
+
+	nodes, _, err := c.Nodes.GetNodes(context.Background(), nil)
+
+	assert.NoError(t, err)
+	n := nodes[0]
+	assert.True(t, *n.IsShared)
+	assert.Equal(t, "https://content-na.drive.amazonaws.com/cdproxy/templink/abc", *n.TempLink)
+
+	cp := n.ContentProperties
+	assert.Equal(t, uint64(2), *cp.Version)
+	assert.Equal(t, uint64(1048576), *cp.Size)
+	assert.Equal(t, "6df23dc03f9b54cc38a0fc1483df6e21", *cp.MD5)
+	assert.Equal(t, "video/mp4", *cp.ContentType)
+	assert.Equal(t, "mp4", *cp.Extension)
+	assert.Equal(t, "2015-04-01 10:00:00 +0000 UTC", cp.ContentDate.String())
+	assert.Nil(t, cp.Image)
+	assert.Equal(t, uint64(1920), *cp.Video.Width)
+	assert.Equal(t, 12.5, *cp.Video.Duration)
+	assert.Equal(t, "h264", *cp.Video.VideoCodec)
+	assert.Equal(t, 90, *cp.Video.Rotate)
+}
+
+func TestFolder_createFolder(t *testing.T) {
+	r := *NewMockResponseOkString(`{ "id": "newFolder", "name": "bar", "kind": "FOLDER", "parents": ["parentId"] }`)
+	c := NewMockClient(r)
+	id := "parentId"
+	folder := &Folder{&Node{Id: &id, service: c.Nodes}}
+
+	created, resp, err := folder.CreateFolder(context.Background(), "bar")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "newFolder", *created.Id)
+	assert.True(t, created.IsFolder())
+	assert.Equal(t, "POST", resp.Request.Method)
+	assert.Equal(t, `{"name":"bar","kind":"FOLDER","parents":["parentId"]}`+"\n", string(mockTransportOf(c).bodies[0]))
+}
+
+func TestFolder_mkdirAll(t *testing.T) {
+	c := NewMockClientSequence(
+		// "a" exists
+		*NewMockResponseOkString(`{ "count": 1, "data": [{ "id": "a", "name": "a", "kind": "FOLDER" }] }`),
+		// "b" does not exist, but gets created concurrently
+		*NewMockResponseOkString(`{ "count": 0, "data": [] }`),
+		MockResponse{Code: 409, Body: []byte(`{"code":"NAME_ALREADY_EXISTS","message":"Node with the name b already exists"}`)},
+		*NewMockResponseOkString(`{ "count": 1, "data": [{ "id": "b", "name": "b", "kind": "FOLDER" }] }`),
+		// "c" does not exist
+		*NewMockResponseOkString(`{ "count": 0, "data": [] }`),
+		*NewMockResponseOkString(`{ "id": "c", "name": "c", "kind": "FOLDER" }`),
+	)
+	id := "root"
+	root := &Folder{&Node{Id: &id, service: c.Nodes}}
+
+	folder, resps, err := root.MkdirAll(context.Background(), "a", "b", "c")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "c", *folder.Id)
+	assert.Equal(t, 6, len(resps))
+	assert.Equal(t, "POST", mockTransportOf(c).reqs[5].Method)
+}
+
+func TestFolder_getNodeNotFound(t *testing.T) {
+	c := NewMockClient(*NewMockResponseOkString(`{ "count": 0, "data": [] }`))
+	id := "root"
+	root := &Folder{&Node{Id: &id, service: c.Nodes}}
+
+	_, _, err := root.GetNode(context.Background(), "missing")
+
+	assert.True(t, IsNotFound(err))
+	assert.Equal(t, "No node 'missing' found", err.Error())
+}
+
+func TestNode_rename(t *testing.T) {
+	r := *NewMockResponseOkString(`{ "id": "fooo1", "name": "bar.zip", "kind": "FILE" }`)
+	c := NewMockClient(r)
+	id := "fooo1"
+	n := &Node{Id: &id, service: c.Nodes}
+
+	renamed, resp, err := n.Rename(context.Background(), "bar.zip")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "bar.zip", *renamed.Name)
+	assert.Equal(t, "PATCH", resp.Request.Method)
+	assert.Equal(t, "https://drive.amazonaws.com/drive/v1/nodes/fooo1", resp.Request.URL.String())
+	assert.Equal(t, `{"name":"bar.zip"}`+"\n", string(mockTransportOf(c).bodies[0]))
+}
+
+func TestNode_move(t *testing.T) {
+	r := *NewMockResponseOkString(`{ "id": "fooo1", "name": "foo.zip", "kind": "FILE", "parents": ["dest"] }`)
+	c := NewMockClient(r)
+	id, fromId, toId := "fooo1", "staging", "dest"
+	n := &Node{Id: &id, service: c.Nodes}
+	from := &Folder{&Node{Id: &fromId, service: c.Nodes}}
+	to := &Folder{&Node{Id: &toId, service: c.Nodes}}
+
+	moved, resp, err := n.Move(context.Background(), from, to)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"dest"}, moved.Parents)
+	assert.Equal(t, "POST", resp.Request.Method)
+	assert.Equal(t, "https://drive.amazonaws.com/drive/v1/nodes/dest/children", resp.Request.URL.String())
+	assert.Equal(t, `{"childId":"fooo1","fromParent":"staging"}`+"\n", string(mockTransportOf(c).bodies[0]))
+}
+
+func TestNode_moveConflict(t *testing.T) {
+	c := NewMockClient(MockResponse{Code: 409, Body: []byte(`{"code":"NAME_ALREADY_EXISTS","message":"Node with the name foo.zip already exists"}`)})
+	id, fromId, toId := "fooo1", "staging", "dest"
+	n := &Node{Id: &id, service: c.Nodes}
+	from := &Folder{&Node{Id: &fromId, service: c.Nodes}}
+	to := &Folder{&Node{Id: &toId, service: c.Nodes}}
+
+	_, _, err := n.Move(context.Background(), from, to)
+
+	assert.True(t, IsConflict(err))
+}
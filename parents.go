@@ -0,0 +1,179 @@
+// Copyright (c) 2015 Serge Gebhardt. All rights reserved.
+//
+// Use of this source code is governed by the ISC
+// license that can be found in the LICENSE file.
+
+package acd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// maxPathDepth bounds the resolution of paths, protecting against cycles in
+// the parent relationships.
+const maxPathDepth = 256
+
+// GetParents gets all folders node n is contained in.
+func (n *Node) GetParents(ctx context.Context) ([]*Folder, []*http.Response, error) {
+	parents := make([]*Folder, 0, len(n.Parents))
+	resps := make([]*http.Response, 0, len(n.Parents))
+
+	for _, id := range n.Parents {
+		p, resp, err := n.service.GetNode(ctx, id)
+		resps = append(resps, resp)
+		if err != nil {
+			return parents, resps, err
+		}
+
+		folder, ok := p.Typed().(*Folder)
+		if !ok {
+			err := errors.New(fmt.Sprintf("Parent '%s' is not a folder", id))
+			return parents, resps, err
+		}
+		parents = append(parents, folder)
+	}
+
+	return parents, resps, nil
+}
+
+// AddParent adds folder as an additional parent of node n, keeping the
+// existing ones. The node is then reachable under both folders.
+func (n *Node) AddParent(ctx context.Context, folder *Folder) (*http.Response, error) {
+	url := fmt.Sprintf("nodes/%s/children/%s", *folder.Id, *n.Id)
+	req, err := n.service.client.NewMetadataRequest(ctx, "PUT", url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := n.service.client.Do(req, nil)
+	if err != nil {
+		return resp, err
+	}
+
+	if !n.hasParent(*folder.Id) {
+		n.Parents = append(n.Parents, *folder.Id)
+	}
+	if n.service.Cache != nil {
+		n.service.Cache.Put(n)
+	}
+	return resp, nil
+}
+
+// RemoveParent removes folder from the parents of node n. A node must keep at
+// least one parent, use Trash to remove it entirely.
+func (n *Node) RemoveParent(ctx context.Context, folder *Folder) (*http.Response, error) {
+	url := fmt.Sprintf("nodes/%s/children/%s", *folder.Id, *n.Id)
+	req, err := n.service.client.NewMetadataRequest(ctx, "DELETE", url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := n.service.client.Do(req, nil)
+	if err != nil {
+		return resp, err
+	}
+
+	parents := make([]string, 0, len(n.Parents))
+	for _, id := range n.Parents {
+		if id != *folder.Id {
+			parents = append(parents, id)
+		}
+	}
+	n.Parents = parents
+	if n.service.Cache != nil {
+		n.service.Cache.Put(n)
+	}
+	return resp, nil
+}
+
+func (n *Node) hasParent(id string) bool {
+	for _, p := range n.Parents {
+		if p == id {
+			return true
+		}
+	}
+	return false
+}
+
+// GetPaths resolves all absolute paths (such as "/a/b/c") node n is reachable
+// under, following every parent up to the root. The names are escaped as by
+// JoinPath, so the paths can be passed to ResolvePath and SplitPath. The paths
+// are sorted.
+func (n *Node) GetPaths(ctx context.Context) ([]string, error) {
+	r := &pathResolver{
+		service: n.service,
+		nodes:   make(map[string]*Node),
+		paths:   make(map[string][]string),
+	}
+
+	paths, err := r.resolve(ctx, n, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// pathResolver resolves the paths of nodes, caching the nodes and paths
+// already seen.
+type pathResolver struct {
+	service *NodesService
+	nodes   map[string]*Node
+	paths   map[string][]string
+}
+
+func (r *pathResolver) resolve(ctx context.Context, n *Node, depth int) ([]string, error) {
+	if n.IsRoot != nil && *n.IsRoot {
+		return []string{"/"}, nil
+	}
+	if depth > maxPathDepth {
+		return nil, errors.New("Maximum path depth exceeded")
+	}
+	if paths, ok := r.paths[*n.Id]; ok {
+		return paths, nil
+	}
+
+	name := ""
+	if n.Name != nil {
+		name = pathEscaper.Replace(*n.Name)
+	}
+
+	var paths []string
+	for _, id := range n.Parents {
+		p, err := r.node(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+
+		parentPaths, err := r.resolve(ctx, p, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		for _, pp := range parentPaths {
+			paths = append(paths, strings.TrimSuffix(pp, "/")+"/"+name)
+		}
+	}
+
+	r.paths[*n.Id] = paths
+	return paths, nil
+}
+
+func (r *pathResolver) node(ctx context.Context, id string) (*Node, error) {
+	if n, ok := r.nodes[id]; ok {
+		return n, nil
+	}
+
+	n, _, err := r.service.GetNode(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	r.nodes[id] = n
+	return n, nil
+}
@@ -0,0 +1,92 @@
+// Copyright (c) 2015 Serge Gebhardt. All rights reserved.
+//
+// Use of this source code is governed by the ISC
+// license that can be found in the LICENSE file.
+
+package acd
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestTree returns a mocked client serving the following nodes:
+//
+//	/ (root)
+//	/albums (albums)
+//	/albums/summer (summer)
+//	/photos (photos)
+//	/photos/beach.jpg, /albums/summer/beach.jpg (beach)
+func newTestTree() *Client {
+	const prefix = "/drive/v1/nodes/"
+	return NewMockClientRoutes(map[string]MockResponse{
+		prefix + "root":   *NewMockResponseOkString(`{ "id": "root", "kind": "FOLDER", "isRoot": true }`),
+		prefix + "albums": *NewMockResponseOkString(`{ "id": "albums", "name": "albums", "kind": "FOLDER", "parents": ["root"] }`),
+		prefix + "summer": *NewMockResponseOkString(`{ "id": "summer", "name": "summer", "kind": "FOLDER", "parents": ["albums"] }`),
+		prefix + "photos": *NewMockResponseOkString(`{ "id": "photos", "name": "photos", "kind": "FOLDER", "parents": ["root"] }`),
+		prefix + "beach":  *NewMockResponseOkString(`{ "id": "beach", "name": "beach.jpg", "kind": "FILE", "parents": ["photos", "summer"] }`),
+	})
+}
+
+func TestNode_getParents(t *testing.T) {
+	c := newTestTree()
+	beach, _, err := c.Nodes.GetNode(context.Background(), "beach")
+	assert.NoError(t, err)
+
+	parents, _, err := beach.GetParents(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(parents))
+	assert.Equal(t, "photos", *parents[0].Name)
+	assert.Equal(t, "summer", *parents[1].Name)
+}
+
+func TestNode_getPaths(t *testing.T) {
+	c := newTestTree()
+	beach, _, err := c.Nodes.GetNode(context.Background(), "beach")
+	assert.NoError(t, err)
+
+	paths, err := beach.GetPaths(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"/albums/summer/beach.jpg", "/photos/beach.jpg"}, paths)
+}
+
+func TestNode_getPathsEscaped(t *testing.T) {
+	c := NewMockClientRoutes(map[string]MockResponse{
+		"/drive/v1/nodes/root": *NewMockResponseOkString(`{ "id": "root", "kind": "FOLDER", "isRoot": true }`),
+	})
+	id, name := "odd", `a/b\c`
+	n := &Node{Id: &id, Name: &name, Parents: []string{"root"}, service: c.Nodes}
+
+	paths, err := n.GetPaths(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{`/a\/b\\c`}, paths)
+	names, err := SplitPath(paths[0])
+	assert.NoError(t, err)
+	assert.Equal(t, []string{name}, names)
+}
+
+func TestNode_addAndRemoveParent(t *testing.T) {
+	c := NewMockClient(MockResponse{Code: 200})
+	id, folderId := "beach", "summer"
+	n := &Node{Id: &id, Parents: []string{"photos"}, service: c.Nodes}
+	folder := &Folder{&Node{Id: &folderId, service: c.Nodes}}
+
+	resp, err := n.AddParent(context.Background(), folder)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "PUT", resp.Request.Method)
+	assert.Equal(t, "https://drive.amazonaws.com/drive/v1/nodes/summer/children/beach", resp.Request.URL.String())
+	assert.Equal(t, []string{"photos", "summer"}, n.Parents)
+
+	resp, err = n.RemoveParent(context.Background(), folder)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "DELETE", resp.Request.Method)
+	assert.Equal(t, "https://drive.amazonaws.com/drive/v1/nodes/summer/children/beach", resp.Request.URL.String())
+	assert.Equal(t, []string{"photos"}, n.Parents)
+}
@@ -0,0 +1,151 @@
+// Copyright (c) 2015 Serge Gebhardt. All rights reserved.
+//
+// Use of this source code is governed by the ISC
+// license that can be found in the LICENSE file.
+
+package acd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ResolvePath gets the node at the absolute path p, such as "/a/b/c". Empty
+// segments and "." are ignored, ".." refers to the parent folder. A backslash
+// escapes the next character, e.g. "/a\/b" refers to the node named "a/b" in
+// the root folder. If a segment does not exist, the error is a
+// *PathNotFoundError. Returns the list of HTTP responses.
+func (s *NodesService) ResolvePath(ctx context.Context, p string) (*Node, []*http.Response, error) {
+	names, err := SplitPath(p)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	root, resp, err := s.GetRoot(ctx)
+	resps := []*http.Response{resp}
+	if err != nil {
+		return nil, resps, err
+	}
+
+	n, walkResps, err := root.WalkNodes(ctx, names...)
+	resps = append(resps, walkResps...)
+	if err != nil {
+		if IsNotFound(err) {
+			i := len(walkResps) - 1
+			err = &PathNotFoundError{
+				Path:    p,
+				Segment: names[i],
+				Parent:  "/" + JoinPath(names[:i]...),
+			}
+		}
+		return nil, resps, err
+	}
+
+	return n, resps, nil
+}
+
+// Stat gets the node at the absolute path p (see ResolvePath), typed as either
+// *File or *Folder.
+func (s *NodesService) Stat(ctx context.Context, p string) (interface{}, error) {
+	n, _, err := s.ResolvePath(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+
+	return n.Typed(), nil
+}
+
+// ListPath gets the children of the folder at the absolute path p (see
+// ResolvePath). If p is a file, the list only contains the file itself.
+func (s *NodesService) ListPath(ctx context.Context, p string, opts *NodeListOptions) ([]*Node, error) {
+	n, _, err := s.ResolvePath(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+
+	folder, ok := n.Typed().(*Folder)
+	if !ok {
+		return []*Node{n}, nil
+	}
+
+	nodes, _, err := folder.GetAllChildren(ctx, opts)
+	return nodes, err
+}
+
+// PathNotFoundError is returned when resolving a path with a missing segment.
+// It matches ErrNotFound.
+type PathNotFoundError struct {
+	Path    string // path being resolved
+	Segment string // name of the first missing segment
+	Parent  string // path of the folder the segment is missing from
+}
+
+func (e *PathNotFoundError) Error() string {
+	return fmt.Sprintf("No node '%s' found in '%s' (resolving '%s')", e.Segment, e.Parent, e.Path)
+}
+
+// Is reports whether target is ErrNotFound. Used by errors.Is.
+func (e *PathNotFoundError) Is(target error) bool {
+	return target == ErrNotFound
+}
+
+// SplitPath splits the path p into the names of its segments, resolving "."
+// and "..". A backslash escapes the next character, allowing names that
+// contain slashes or backslashes.
+func SplitPath(p string) ([]string, error) {
+	names := make([]string, 0, strings.Count(p, "/")+1)
+
+	var name strings.Builder
+	escaped := false
+	literal := false // whether name contains an escaped character
+
+	flush := func() {
+		switch n := name.String(); {
+		case n == "" || (n == "." && !literal):
+		case n == ".." && !literal:
+			if len(names) > 0 {
+				names = names[:len(names)-1]
+			}
+		default:
+			names = append(names, n)
+		}
+		name.Reset()
+		literal = false
+	}
+
+	for _, r := range p {
+		switch {
+		case escaped:
+			name.WriteRune(r)
+			escaped = false
+			literal = true
+		case r == '\\':
+			escaped = true
+		case r == '/':
+			flush()
+		default:
+			name.WriteRune(r)
+		}
+	}
+	if escaped {
+		return nil, errors.New(fmt.Sprintf("Trailing backslash in path '%s'", p))
+	}
+	flush()
+
+	return names, nil
+}
+
+// JoinPath joins names into a path, escaping slashes and backslashes in the
+// names. It is the inverse of SplitPath, without the leading slash.
+func JoinPath(names ...string) string {
+	escaped := make([]string, len(names))
+	for i, n := range names {
+		escaped[i] = pathEscaper.Replace(n)
+	}
+	return strings.Join(escaped, "/")
+}
+
+var pathEscaper = strings.NewReplacer(`\`, `\\`, `/`, `\/`)
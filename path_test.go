@@ -0,0 +1,83 @@
+// Copyright (c) 2015 Serge Gebhardt. All rights reserved.
+//
+// Use of this source code is governed by the ISC
+// license that can be found in the LICENSE file.
+
+package acd
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPath_splitPath(t *testing.T) {
+	tests := []struct {
+		path  string
+		names []string
+	}{
+		{"/", []string{}},
+		{"", []string{}},
+		{"/a/b/c", []string{"a", "b", "c"}},
+		{"a//b/", []string{"a", "b"}},
+		{"/a/./b/../c", []string{"a", "c"}},
+		{"/../a", []string{"a"}},
+		{`/a\/b/c`, []string{"a/b", "c"}},
+		{`/a\\/b`, []string{`a\`, "b"}},
+		{`/\.\./x`, []string{"..", "x"}},
+	}
+
+	for _, tt := range tests {
+		names, err := SplitPath(tt.path)
+		assert.NoError(t, err, tt.path)
+		assert.Equal(t, tt.names, names, tt.path)
+	}
+
+	_, err := SplitPath(`/a\`)
+	assert.Error(t, err)
+}
+
+func TestPath_joinPath(t *testing.T) {
+	p := JoinPath("a/b", `c\d`, "e")
+	assert.Equal(t, `a\/b/c\\d/e`, p)
+
+	names, err := SplitPath(p)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a/b", `c\d`, "e"}, names)
+}
+
+func TestPath_resolvePath(t *testing.T) {
+	c := NewMockClientSequence(
+		*NewMockResponseOkString(`{ "count": 1, "data": [{ "id": "root", "kind": "FOLDER", "isRoot": true }] }`),
+		*NewMockResponseOkString(`{ "count": 1, "data": [{ "id": "a", "name": "a", "kind": "FOLDER" }] }`),
+		*NewMockResponseOkString(`{ "count": 1, "data": [{ "id": "b", "name": "b.txt", "kind": "FILE" }] }`),
+	)
+
+	f, err := c.Nodes.Stat(context.Background(), "/x/../a/./b.txt")
+
+	assert.NoError(t, err)
+	assert.IsType(t, &File{}, f)
+	assert.Equal(t, "b", *f.(*File).Id)
+	assert.Equal(t, 3, len(mockTransportOf(c).reqs))
+}
+
+func TestPath_resolvePathNotFound(t *testing.T) {
+	c := NewMockClientSequence(
+		*NewMockResponseOkString(`{ "count": 1, "data": [{ "id": "root", "kind": "FOLDER", "isRoot": true }] }`),
+		*NewMockResponseOkString(`{ "count": 1, "data": [{ "id": "a", "name": "a", "kind": "FOLDER" }] }`),
+		*NewMockResponseOkString(`{ "count": 0, "data": [] }`),
+	)
+
+	_, resps, err := c.Nodes.ResolvePath(context.Background(), "/a/missing/c")
+
+	assert.True(t, IsNotFound(err))
+	assert.Equal(t, 3, len(resps))
+
+	var notFound *PathNotFoundError
+	assert.True(t, errors.As(err, &notFound))
+	assert.Equal(t, "missing", notFound.Segment)
+	assert.Equal(t, "/a", notFound.Parent)
+	assert.Equal(t, "/a/missing/c", notFound.Path)
+}
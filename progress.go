@@ -0,0 +1,195 @@
+// Copyright (c) 2015 Serge Gebhardt. All rights reserved.
+//
+// Use of this source code is governed by the ISC
+// license that can be found in the LICENSE file.
+
+package acd
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// progressInterval is the minimum interval between two progress reports.
+const progressInterval = 100 * time.Millisecond
+
+// Progress reports the progress of a content transfer.
+type Progress struct {
+	// Name of the node transferred, and its id. The id of an uploaded node
+	// is only reported once the upload is done.
+	Name   string
+	NodeId string
+
+	// Bytes transferred so far.
+	Transferred int64
+
+	// Total bytes to transfer, -1 if unknown.
+	Total int64
+
+	// Average transfer rate in bytes per second.
+	Rate float64
+
+	// Estimated time remaining, -1 if unknown.
+	ETA time.Duration
+
+	// Whether the transfer is finished (successfully or not).
+	Done bool
+}
+
+// ProgressFunc is called with the progress of a transfer. It is called at most
+// every 100ms per transfer, and a last time when the transfer is done. If
+// several transfers share the context (e.g. Folder.UploadDir), it is called
+// concurrently for each of them; use Progress.Name and Progress.NodeId to tell
+// them apart.
+type ProgressFunc func(Progress)
+
+type progressKey struct{}
+
+// WithProgress returns a context which reports the progress of content
+// transfers made with it (uploads, downloads and content readers) to fn.
+// Metadata requests are not reported, nor are the internal reads of
+// FileReader.Size, FileReader.ReadAt and the header read of
+// Cipher.OpenRange.
+func WithProgress(ctx context.Context, fn ProgressFunc) context.Context {
+	return context.WithValue(ctx, progressKey{}, fn)
+}
+
+// ProgressChan returns a ProgressFunc sending to ch. Reports are dropped
+// while ch is not ready to receive, except the final one.
+func ProgressChan(ch chan<- Progress) ProgressFunc {
+	return func(p Progress) {
+		if p.Done {
+			ch <- p
+			return
+		}
+		select {
+		case ch <- p:
+		default:
+		}
+	}
+}
+
+// withoutProgress returns ctx without its ProgressFunc, for reads which are
+// not transfers of their own.
+func withoutProgress(ctx context.Context) context.Context {
+	if progressFromContext(ctx) == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, progressKey{}, ProgressFunc(nil))
+}
+
+// progressFromContext returns the ProgressFunc attached to ctx, or nil.
+func progressFromContext(ctx context.Context) ProgressFunc {
+	fn, _ := ctx.Value(progressKey{}).(ProgressFunc)
+	return fn
+}
+
+// progressTracker accumulates transferred bytes and reports them.
+type progressTracker struct {
+	fn    ProgressFunc
+	name  string
+	total int64
+	start time.Time
+
+	mu       sync.Mutex
+	nodeId   string
+	n        int64
+	reported time.Time
+	done     bool
+}
+
+// newProgressTracker returns a tracker of the transfer of the given node,
+// reporting to the ProgressFunc attached to ctx, or nil if there is none.
+func newProgressTracker(ctx context.Context, total int64, name, nodeId string) *progressTracker {
+	fn := progressFromContext(ctx)
+	if fn == nil {
+		return nil
+	}
+	return &progressTracker{fn: fn, name: name, nodeId: nodeId, total: total, start: time.Now()}
+}
+
+// setNodeId sets the id of the node reported, once known.
+func (t *progressTracker) setNodeId(id string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.nodeId = id
+}
+
+func (t *progressTracker) add(n int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.n += int64(n)
+	if now := time.Now(); now.Sub(t.reported) >= progressInterval {
+		t.reported = now
+		t.report(now)
+	}
+}
+
+// finish reports the final progress, once.
+func (t *progressTracker) finish() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.done {
+		return
+	}
+	t.done = true
+	t.report(time.Now())
+}
+
+func (t *progressTracker) report(now time.Time) {
+	p := Progress{
+		Name:        t.name,
+		NodeId:      t.nodeId,
+		Transferred: t.n,
+		Total:       t.total,
+		ETA:         -1,
+		Done:        t.done,
+	}
+
+	if elapsed := now.Sub(t.start).Seconds(); elapsed > 0 {
+		p.Rate = float64(t.n) / elapsed
+	}
+	if t.total >= 0 && p.Rate > 0 {
+		p.ETA = time.Duration(float64(t.total-t.n) / p.Rate * float64(time.Second))
+	}
+
+	t.fn(p)
+}
+
+// progressWriter reports the bytes written through it.
+type progressWriter struct {
+	w       io.Writer
+	tracker *progressTracker
+}
+
+func (w *progressWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.tracker.add(n)
+	return n, err
+}
+
+// progressReadCloser reports the bytes read through it, and finishes when
+// closed.
+type progressReadCloser struct {
+	r       io.ReadCloser
+	tracker *progressTracker
+}
+
+func (r *progressReadCloser) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.tracker.add(n)
+	if err == io.EOF {
+		r.tracker.finish()
+	}
+	return n, err
+}
+
+func (r *progressReadCloser) Close() error {
+	r.tracker.finish()
+	return r.r.Close()
+}
@@ -0,0 +1,148 @@
+// Copyright (c) 2015 Serge Gebhardt. All rights reserved.
+//
+// Use of this source code is governed by the ISC
+// license that can be found in the LICENSE file.
+
+package acd
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// collectProgress returns a context collecting progress reports into ps.
+func collectProgress(ps *[]Progress) context.Context {
+	return WithProgress(context.Background(), func(p Progress) {
+		*ps = append(*ps, p)
+	})
+}
+
+func TestProgress_download(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go-acd")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	c := NewMockContentClient([]byte(testContent))
+	f := newTestFile(c, nil)
+
+	var ps []Progress
+	_, err = f.Download(collectProgress(&ps), filepath.Join(dir, "foo"))
+
+	assert.NoError(t, err)
+	last := ps[len(ps)-1]
+	assert.True(t, last.Done)
+	assert.Equal(t, int64(len(testContent)), last.Transferred)
+	assert.Equal(t, int64(len(testContent)), last.Total)
+	assert.Equal(t, "fooo1", last.NodeId)
+}
+
+func TestProgress_openRange(t *testing.T) {
+	c := NewMockContentClient([]byte(testContent))
+	f := newTestFile(c, nil)
+
+	var ps []Progress
+	body, _, err := f.OpenRange(collectProgress(&ps), 6, -1)
+	assert.NoError(t, err)
+	_, err = ioutil.ReadAll(body)
+	assert.NoError(t, err)
+	body.Close()
+
+	last := ps[len(ps)-1]
+	assert.True(t, last.Done)
+	assert.Equal(t, int64(30), last.Transferred)
+	assert.Equal(t, int64(30), last.Total)
+	assert.Equal(t, 1, countDone(ps))
+}
+
+func TestProgress_internalReadsNotReported(t *testing.T) {
+	c := NewMockContentClient([]byte(testContent))
+	f := newTestFile(c, nil)
+
+	var ps []Progress
+	r := f.NewReader(collectProgress(&ps))
+	defer r.Close()
+	size, err := r.Size()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(testContent)), size)
+	p := make([]byte, 4)
+	_, err = r.ReadAt(p, 2)
+	assert.NoError(t, err)
+
+	assert.Equal(t, 0, len(ps))
+}
+
+func TestProgress_cipherOpenRange(t *testing.T) {
+	cipher := newTestCipher(t, false)
+	data := encryptAll(t, cipher, testPlaintext(2*encChunkSize))
+	f := newTestFile(NewMockContentClient(data), nil)
+
+	var ps []Progress
+	body, _, err := cipher.OpenRange(collectProgress(&ps), f, encChunkSize+1, -1)
+	assert.NoError(t, err)
+	_, err = ioutil.ReadAll(body)
+	assert.NoError(t, err)
+	body.Close()
+
+	assert.Equal(t, 1, countDone(ps))
+}
+
+func TestProgress_upload(t *testing.T) {
+	c := NewMockClient(*NewMockResponseOkString(`{ "id": "newFile", "name": "bar", "kind": "FILE" }`))
+	id := "parentId"
+	folder := &Folder{&Node{Id: &id, service: c.Nodes}}
+
+	var ps []Progress
+	content := strings.Repeat("x", 1000)
+	opts := &UploadOptions{Size: int64(len(content))}
+	_, resp, err := folder.UploadReader(collectProgress(&ps), strings.NewReader(content), "bar", opts)
+
+	assert.NoError(t, err)
+	last := ps[len(ps)-1]
+	assert.True(t, last.Done)
+	assert.Equal(t, resp.Request.ContentLength, last.Total)
+	assert.Equal(t, last.Total, last.Transferred)
+	assert.Equal(t, time.Duration(0), last.ETA)
+	assert.Equal(t, "bar", last.Name)
+	assert.Equal(t, "newFile", last.NodeId)
+}
+
+func TestProgress_metadataNotReported(t *testing.T) {
+	c := NewMockClient(*NewMockResponseOkString(`{ "id": "fooo1" }`))
+	id := "fooo1"
+	n := &Node{Id: &id, service: c.Nodes}
+
+	var ps []Progress
+	_, err := n.GetMetadata(collectProgress(&ps))
+
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(ps))
+}
+
+func TestProgress_chan(t *testing.T) {
+	ch := make(chan Progress, 1)
+	fn := ProgressChan(ch)
+
+	fn(Progress{Transferred: 1})
+	fn(Progress{Transferred: 2}) // dropped, channel full
+	assert.Equal(t, int64(1), (<-ch).Transferred)
+
+	fn(Progress{Transferred: 3, Done: true})
+	assert.True(t, (<-ch).Done)
+}
+
+func countDone(ps []Progress) int {
+	n := 0
+	for _, p := range ps {
+		if p.Done {
+			n++
+		}
+	}
+	return n
+}
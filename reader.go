@@ -0,0 +1,169 @@
+// Copyright (c) 2015 Serge Gebhardt. All rights reserved.
+//
+// Use of this source code is governed by the ISC
+// license that can be found in the LICENSE file.
+
+package acd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// FileReader provides random access to the content of a file on the Amazon
+// Cloud Drive, backed by HTTP Range requests. It implements io.ReadSeeker,
+// io.ReaderAt and io.Closer. Read and Seek must not be called concurrently,
+// ReadAt is safe for concurrent use.
+type FileReader struct {
+	file *File
+	ctx  context.Context
+
+	size   int64 // -1 if not known yet
+	offset int64
+	body   io.ReadCloser // streams the content from offset, nil if not open
+}
+
+// NewReader returns a FileReader for the content of file f. No request is made
+// until the first read. The caller must close the reader when done.
+func (f *File) NewReader(ctx context.Context) *FileReader {
+	size := int64(-1)
+	if f.ContentProperties != nil && f.ContentProperties.Size != nil {
+		size = int64(*f.ContentProperties.Size)
+	}
+
+	return &FileReader{file: f, ctx: ctx, size: size}
+}
+
+// Size returns the size of the file content. If not known from the node's
+// metadata, the size is queried from the server.
+func (r *FileReader) Size() (int64, error) {
+	if r.size >= 0 {
+		return r.size, nil
+	}
+
+	body, resp, err := r.file.OpenRange(withoutProgress(r.ctx), 0, 1)
+	if err != nil {
+		return 0, err
+	}
+	body.Close()
+
+	size, err := contentRangeSize(resp)
+	if err != nil {
+		return 0, err
+	}
+
+	r.size = size
+	return size, nil
+}
+
+// Read implements io.Reader.
+func (r *FileReader) Read(p []byte) (int, error) {
+	if r.size >= 0 && r.offset >= r.size {
+		return 0, io.EOF
+	}
+
+	if r.body == nil {
+		body, _, err := r.file.OpenRange(r.ctx, r.offset, -1)
+		if err != nil {
+			return 0, err
+		}
+		r.body = body
+	}
+
+	n, err := r.body.Read(p)
+	r.offset += int64(n)
+	return n, err
+}
+
+// Seek implements io.Seeker. Seeking does not make a request, the content is
+// fetched from the new offset on the next Read.
+func (r *FileReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += r.offset
+	case io.SeekEnd:
+		size, err := r.Size()
+		if err != nil {
+			return r.offset, err
+		}
+		offset += size
+	default:
+		return r.offset, errors.New("Invalid whence")
+	}
+
+	if offset < 0 {
+		return r.offset, errors.New("Negative position")
+	}
+
+	if offset != r.offset {
+		r.closeBody()
+		r.offset = offset
+	}
+	return offset, nil
+}
+
+// ReadAt implements io.ReaderAt. Each call makes its own Range request, which
+// is not reported as progress.
+func (r *FileReader) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errors.New("Negative offset")
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if r.size >= 0 && off >= r.size {
+		return 0, io.EOF
+	}
+
+	body, _, err := r.file.OpenRange(withoutProgress(r.ctx), off, int64(len(p)))
+	if err != nil {
+		return 0, err
+	}
+	defer body.Close()
+
+	n, err := io.ReadFull(body, p)
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+	return n, err
+}
+
+// Close implements io.Closer.
+func (r *FileReader) Close() error {
+	return r.closeBody()
+}
+
+func (r *FileReader) closeBody() error {
+	if r.body == nil {
+		return nil
+	}
+
+	err := r.body.Close()
+	r.body = nil
+	return err
+}
+
+// contentRangeSize returns the complete length from the Content-Range header
+// of a 206 response, e.g. "bytes 0-0/1234".
+func contentRangeSize(resp *http.Response) (int64, error) {
+	if resp.StatusCode != http.StatusPartialContent {
+		if resp.ContentLength >= 0 {
+			return resp.ContentLength, nil
+		}
+		return 0, errors.New("Unknown content size")
+	}
+
+	cr := resp.Header.Get("Content-Range")
+	i := strings.LastIndex(cr, "/")
+	if i < 0 || cr[i+1:] == "*" {
+		return 0, errors.New(fmt.Sprintf("Invalid Content-Range '%s'", cr))
+	}
+
+	return strconv.ParseInt(cr[i+1:], 10, 64)
+}
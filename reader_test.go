@@ -0,0 +1,170 @@
+// Copyright (c) 2015 Serge Gebhardt. All rights reserved.
+//
+// Use of this source code is governed by the ISC
+// license that can be found in the LICENSE file.
+
+package acd
+
+import (
+	"context"
+	"crypto/md5"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testContent = "0123456789abcdefghijklmnopqrstuvwxyz"
+
+func newTestFile(c *Client, size *uint64) *File {
+	id := "fooo1"
+	n := &Node{Id: &id, service: c.Nodes}
+	if size != nil {
+		n.ContentProperties = &ContentProperties{Size: size}
+	}
+	return &File{n}
+}
+
+func TestFile_open(t *testing.T) {
+	c := NewMockContentClient([]byte(testContent))
+	f := newTestFile(c, nil)
+
+	body, _, err := f.Open(context.Background())
+	assert.NoError(t, err)
+	defer body.Close()
+
+	data, err := ioutil.ReadAll(body)
+	assert.NoError(t, err)
+	assert.Equal(t, testContent, string(data))
+}
+
+func TestFile_openRange(t *testing.T) {
+	c := NewMockContentClient([]byte(testContent))
+	f := newTestFile(c, nil)
+
+	body, resp, err := f.OpenRange(context.Background(), 10, 5)
+	assert.NoError(t, err)
+	defer body.Close()
+
+	data, err := ioutil.ReadAll(body)
+	assert.NoError(t, err)
+	assert.Equal(t, "abcde", string(data))
+	assert.Equal(t, "bytes=10-14", resp.Request.Header.Get("Range"))
+}
+
+func TestFile_openRangeEmpty(t *testing.T) {
+	c := NewMockContentClient([]byte(testContent))
+	f := newTestFile(c, nil)
+
+	body, _, err := f.OpenRange(context.Background(), 10, 0)
+	assert.NoError(t, err)
+	defer body.Close()
+
+	data, err := ioutil.ReadAll(body)
+	assert.NoError(t, err)
+	assert.Equal(t, "", string(data))
+	assert.Equal(t, 0, len(c.httpClient.Transport.(*mockContentTransport).reqs))
+}
+
+func TestFileReader_seekAndRead(t *testing.T) {
+	c := NewMockContentClient([]byte(testContent))
+	r := newTestFile(c, nil).NewReader(context.Background())
+	defer r.Close()
+
+	size, err := r.Size()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(testContent)), size)
+
+	pos, err := r.Seek(-3, io.SeekEnd)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(33), pos)
+
+	data, err := ioutil.ReadAll(r)
+	assert.NoError(t, err)
+	assert.Equal(t, "xyz", string(data))
+
+	pos, err = r.Seek(5, io.SeekStart)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), pos)
+
+	buf := make([]byte, 4)
+	_, err = io.ReadFull(r, buf)
+	assert.NoError(t, err)
+	assert.Equal(t, "5678", string(buf))
+}
+
+func TestFileReader_readAt(t *testing.T) {
+	size := uint64(len(testContent))
+	c := NewMockContentClient([]byte(testContent))
+	r := newTestFile(c, &size).NewReader(context.Background())
+
+	buf := make([]byte, 3)
+	n, err := r.ReadAt(buf, 20)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, n)
+	assert.Equal(t, "klm", string(buf))
+
+	buf = make([]byte, 10)
+	n, err = r.ReadAt(buf, 30)
+	assert.Equal(t, io.EOF, err)
+	assert.Equal(t, 6, n)
+	assert.Equal(t, "uvwxyz", string(buf[:n]))
+
+	n, err = r.ReadAt(buf, 100)
+	assert.Equal(t, io.EOF, err)
+	assert.Equal(t, 0, n)
+}
+
+func TestFile_downloadResume(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go-acd")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "foo")
+	err = ioutil.WriteFile(path+partialSuffix, []byte(testContent[:10]), 0666)
+	assert.NoError(t, err)
+
+	size := uint64(len(testContent))
+	sum := fmt.Sprintf("%x", md5.Sum([]byte(testContent)))
+	c := NewMockContentClient([]byte(testContent))
+	f := newTestFile(c, &size)
+	f.ContentProperties.MD5 = &sum
+
+	resp, err := f.DownloadResume(context.Background(), path)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "bytes=10-", resp.Request.Header.Get("Range"))
+	data, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, testContent, string(data))
+	_, err = os.Stat(path + partialSuffix)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestFile_downloadResumeMismatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go-acd")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "foo")
+	err = ioutil.WriteFile(path+partialSuffix, []byte("garbage"), 0666)
+	assert.NoError(t, err)
+
+	size := uint64(len(testContent))
+	sum := fmt.Sprintf("%x", md5.Sum([]byte(testContent)))
+	c := NewMockContentClient([]byte(testContent))
+	f := newTestFile(c, &size)
+	f.ContentProperties.MD5 = &sum
+
+	_, err = f.DownloadResume(context.Background(), path)
+
+	assert.Error(t, err)
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(path + partialSuffix)
+	assert.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,161 @@
+// Copyright (c) 2015 Serge Gebhardt. All rights reserved.
+//
+// Use of this source code is governed by the ISC
+// license that can be found in the LICENSE file.
+
+package acd
+
+import (
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy configures how Client.Do retries requests that failed with a
+// transient error. Only requests with an idempotent method (GET, HEAD,
+// OPTIONS, PUT, DELETE) are retried, and only if their body is empty or can be
+// rewound.
+type RetryPolicy struct {
+	// Maximum number of attempts, including the first one. Values below 2
+	// disable retries.
+	MaxAttempts int
+
+	// Delay before the first retry. The delay doubles with each further
+	// attempt, up to MaxDelay.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+
+	// Fraction (between 0 and 1) of the delay that is randomized, to avoid
+	// many clients retrying in lockstep.
+	Jitter float64
+
+	// HTTP status codes considered transient. Errors from the underlying
+	// transport (no response at all) are always considered transient.
+	RetryableStatus []int
+}
+
+// DefaultRetryPolicy returns the retry policy used by NewClient.
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts: 5,
+		BaseDelay:   500 * time.Millisecond,
+		MaxDelay:    30 * time.Second,
+		Jitter:      0.5,
+		RetryableStatus: []int{
+			http.StatusTooManyRequests,
+			http.StatusInternalServerError,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		},
+	}
+}
+
+// shouldRetry returns whether a request which failed on the given attempt
+// (starting at 1) should be attempted again.
+func (p *RetryPolicy) shouldRetry(req *http.Request, resp *http.Response, attempt int) bool {
+	if p == nil || attempt >= p.MaxAttempts {
+		return false
+	}
+
+	switch req.Method {
+	case "GET", "HEAD", "OPTIONS", "PUT", "DELETE":
+	default:
+		return false
+	}
+
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+
+	if resp == nil {
+		return true
+	}
+	for _, c := range p.RetryableStatus {
+		if resp.StatusCode == c {
+			return true
+		}
+	}
+	return false
+}
+
+// delay returns how long to wait before the next attempt, given the failed
+// attempt (starting at 1) and its response (nil if none). A Retry-After header
+// in the response takes precedence over the exponential backoff, but is still
+// bounded by MaxDelay.
+func (p *RetryPolicy) delay(resp *http.Response, attempt int) time.Duration {
+	if d, ok := retryAfter(resp); ok {
+		if p.MaxDelay > 0 && d > p.MaxDelay {
+			d = p.MaxDelay
+		}
+		return d
+	}
+
+	d := p.BaseDelay
+	for i := 1; i < attempt; i++ {
+		d *= 2
+		if p.MaxDelay > 0 && d >= p.MaxDelay {
+			break
+		}
+	}
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+
+	if p.Jitter > 0 {
+		d -= time.Duration(p.Jitter * rand.Float64() * float64(d))
+	}
+	return d
+}
+
+// retryAfter parses the Retry-After header of resp, which is either a number
+// of seconds or an HTTP date.
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	if resp == nil {
+		return 0, false
+	}
+
+	v := resp.Header.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+
+	if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		d := t.Sub(time.Now())
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
+
+// rewindBody resets the body of req so that it can be sent again.
+func rewindBody(req *http.Request) error {
+	if req.GetBody == nil {
+		return nil
+	}
+
+	body, err := req.GetBody()
+	if err != nil {
+		return err
+	}
+	req.Body = body
+	return nil
+}
+
+// drainBody discards the rest of the body and closes it, allowing the
+// underlying connection to be reused.
+func drainBody(body io.ReadCloser) {
+	if body == nil {
+		return
+	}
+	io.Copy(ioutil.Discard, body)
+	body.Close()
+}
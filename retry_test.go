@@ -0,0 +1,124 @@
+// Copyright (c) 2015 Serge Gebhardt. All rights reserved.
+//
+// Use of this source code is governed by the ISC
+// license that can be found in the LICENSE file.
+
+package acd
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testRetryPolicy() *RetryPolicy {
+	p := DefaultRetryPolicy()
+	p.BaseDelay = time.Millisecond
+	p.MaxDelay = 5 * time.Millisecond
+	return p
+}
+
+func TestRetry_transientThenSuccess(t *testing.T) {
+	c := NewMockClientSequence(
+		MockResponse{Code: 503},
+		MockResponse{Code: 429, Header: http.Header{"Retry-After": {"0"}}},
+		*NewMockResponseOkString(`{ "termsOfUse": "1.0.0", "status": "ACTIVE" }`),
+	)
+	c.RetryPolicy = testRetryPolicy()
+
+	info, _, err := c.Account.GetInfo(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, "ACTIVE", *info.Status)
+	assert.Equal(t, 3, len(mockTransportOf(c).reqs))
+}
+
+func TestRetry_maxAttempts(t *testing.T) {
+	c := NewMockClient(MockResponse{Code: 500})
+	c.RetryPolicy = testRetryPolicy()
+	c.RetryPolicy.MaxAttempts = 3
+
+	_, resp, err := c.Account.GetInfo(context.Background())
+
+	assert.Error(t, err)
+	assert.Equal(t, 500, resp.StatusCode)
+	assert.Equal(t, 3, len(mockTransportOf(c).reqs))
+}
+
+func TestRetry_notRetryable(t *testing.T) {
+	c := NewMockClient(MockResponse{Code: 404})
+	c.RetryPolicy = testRetryPolicy()
+
+	_, _, err := c.Account.GetInfo(context.Background())
+
+	assert.True(t, IsNotFound(err))
+	assert.Equal(t, 1, len(mockTransportOf(c).reqs))
+}
+
+func TestRetry_nonIdempotentMethod(t *testing.T) {
+	c := NewMockClient(MockResponse{Code: 503})
+	c.RetryPolicy = testRetryPolicy()
+
+	req, _ := c.NewMetadataRequest(context.Background(), "POST", "nodes", map[string]string{"name": "foo"})
+	_, err := c.Do(req, nil)
+
+	assert.Error(t, err)
+	assert.Equal(t, 1, len(mockTransportOf(c).reqs))
+}
+
+func TestRetry_rewindsBody(t *testing.T) {
+	c := NewMockClientSequence(
+		MockResponse{Code: 503},
+		*NewMockResponseOkString(`{}`),
+	)
+	c.RetryPolicy = testRetryPolicy()
+
+	req, _ := c.NewContentRequest(context.Background(), "PUT", "nodes/foo/content", strings.NewReader("payload"))
+	_, err := c.Do(req, nil)
+
+	assert.NoError(t, err)
+	bodies := mockTransportOf(c).bodies
+	assert.Equal(t, 2, len(bodies))
+	assert.Equal(t, "payload", string(bodies[0]))
+	assert.Equal(t, "payload", string(bodies[1]))
+}
+
+func TestRetry_delay(t *testing.T) {
+	p := &RetryPolicy{BaseDelay: time.Second, MaxDelay: 10 * time.Second}
+
+	assert.Equal(t, time.Second, p.delay(nil, 1))
+	assert.Equal(t, 2*time.Second, p.delay(nil, 2))
+	assert.Equal(t, 8*time.Second, p.delay(nil, 4))
+	assert.Equal(t, 10*time.Second, p.delay(nil, 10))
+
+	resp := &http.Response{Header: http.Header{"Retry-After": {"3"}}}
+	assert.Equal(t, 3*time.Second, p.delay(resp, 1))
+
+	resp = &http.Response{Header: http.Header{"Retry-After": {"120"}}}
+	assert.Equal(t, 10*time.Second, p.delay(resp, 1))
+
+	p.Jitter = 0.5
+	for i := 0; i < 20; i++ {
+		d := p.delay(nil, 2)
+		assert.True(t, d > time.Second && d <= 2*time.Second, "delay %v", d)
+	}
+}
+
+func TestRetry_contextCanceled(t *testing.T) {
+	c := NewMockClient(MockResponse{Code: 503})
+	c.RetryPolicy = testRetryPolicy()
+	c.RetryPolicy.BaseDelay = time.Hour
+	c.RetryPolicy.MaxDelay = time.Hour
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, _, err := c.Account.GetInfo(ctx)
+
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.Equal(t, 1, len(mockTransportOf(c).reqs))
+}
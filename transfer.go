@@ -0,0 +1,414 @@
+// Copyright (c) 2015 Serge Gebhardt. All rights reserved.
+//
+// Use of this source code is governed by the ISC
+// license that can be found in the LICENSE file.
+
+package acd
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// TransferOptions holds the options of Folder.UploadDir and
+// Folder.DownloadDir.
+type TransferOptions struct {
+	// Number of files transferred concurrently. Defaults to 4.
+	Concurrency int
+
+	// Glob patterns (see path.Match) of the files to transfer. Patterns
+	// containing a slash are matched against the path relative to the
+	// transferred directory, the others against the file name. If empty, all
+	// files are transferred.
+	Include []string
+
+	// Glob patterns of the files and directories to leave out, matched like
+	// Include. Excluding a directory leaves out its whole content.
+	Exclude []string
+
+	// Whether to skip files whose destination already has the same size and
+	// MD5. Otherwise existing files are overwritten.
+	SkipIdentical bool
+
+	// Called as each file transfer completes, with the number of transfers
+	// completed so far and the total number of transfers. Calls are
+	// serialized, but come from the transferring goroutines.
+	OnResult func(r *TransferResult, done, total int)
+
+	// Whether to skip uploading new files whose content already exists
+	// anywhere on the drive, see Folder.UploadDeduplicated. The result of a
+	// skipped file holds the existing file, which may be in another folder
+	// and have another name: the destination folder does not get the file.
+	// Ignored by DownloadDir.
+	SkipDuplicates bool
+}
+
+// TransferResult reports the transfer of a single file.
+type TransferResult struct {
+	// Slash-separated path relative to the transferred directory.
+	Path string
+
+	// Remote file, nil if the transfer failed.
+	File *File
+
+	// Whether the file was skipped because it was identical or a duplicate.
+	Skipped bool
+
+	// Error which occurred transferring the file, if any.
+	Err error
+}
+
+// transferJob is a single file to transfer.
+type transferJob struct {
+	relPath string
+	run     func() (*File, bool, error)
+}
+
+// matches returns whether relPath should be transferred, given whether it is
+// a directory.
+func (o *TransferOptions) matches(relPath string, isDir bool) bool {
+	for _, p := range o.Exclude {
+		if matchTransferPattern(p, relPath) {
+			return false
+		}
+	}
+	if isDir || len(o.Include) == 0 {
+		return true
+	}
+	for _, p := range o.Include {
+		if matchTransferPattern(p, relPath) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchTransferPattern(pattern, relPath string) bool {
+	name := relPath
+	if !strings.Contains(pattern, "/") {
+		name = path.Base(relPath)
+	}
+	ok, _ := path.Match(pattern, name)
+	return ok
+}
+
+// runTransfers runs the jobs on a pool of workers and returns their results,
+// in the order of the jobs. The returned error summarizes failed transfers.
+func runTransfers(ctx context.Context, jobs []*transferJob, opts *TransferOptions) ([]*TransferResult, error) {
+	concurrency := opts.Concurrency
+	if concurrency < 1 {
+		concurrency = 4
+	}
+
+	results := make([]*TransferResult, len(jobs))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	var mu sync.Mutex // serializes OnResult
+	done := 0
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				r := &TransferResult{Path: jobs[i].relPath}
+				if err := ctx.Err(); err != nil {
+					r.Err = err
+				} else {
+					r.File, r.Skipped, r.Err = jobs[i].run()
+				}
+				results[i] = r
+
+				if opts.OnResult != nil {
+					mu.Lock()
+					done++
+					opts.OnResult(r, done, len(jobs))
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	for i := range jobs {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	failed := 0
+	for _, r := range results {
+		if r.Err != nil {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return results, errors.New(fmt.Sprintf("%v of %v transfers failed", failed, len(results)))
+	}
+	return results, nil
+}
+
+// UploadDir uploads the content of the local directory localDir into folder f,
+// creating the missing subfolders. opts may be nil. Returns a result for each
+// file. If some transfers failed, the error summarizes them and the details
+// are in the results.
+func (f *Folder) UploadDir(ctx context.Context, localDir string, opts *TransferOptions) ([]*TransferResult, error) {
+	if opts == nil {
+		opts = &TransferOptions{}
+	}
+
+	// remote folders and their children, by slash-separated relative path
+	folders := map[string]*Folder{".": f}
+	children := map[string]map[string]*Node{}
+
+	childrenOf := func(relDir string) (map[string]*Node, error) {
+		if c, ok := children[relDir]; ok {
+			return c, nil
+		}
+		nodes, _, err := folders[relDir].GetAllChildren(ctx, nil)
+		if err != nil {
+			return nil, err
+		}
+		c := make(map[string]*Node, len(nodes))
+		for _, n := range nodes {
+			if n.Name != nil {
+				c[*n.Name] = n
+			}
+		}
+		children[relDir] = c
+		return c, nil
+	}
+
+	var jobs []*transferJob
+	err := filepath.Walk(localDir, func(localPath string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(localDir, localPath)
+		if err != nil {
+			return err
+		}
+		relPath := filepath.ToSlash(rel)
+		if relPath == "." {
+			return nil
+		}
+		if !opts.matches(relPath, fi.IsDir()) {
+			if fi.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		relDir, name := path.Dir(relPath), path.Base(relPath)
+		siblings, err := childrenOf(relDir)
+		if err != nil {
+			return err
+		}
+		existing := siblings[name]
+
+		if fi.IsDir() {
+			if existing != nil {
+				folder, ok := existing.Typed().(*Folder)
+				if !ok {
+					return errors.New(fmt.Sprintf("Node '%s' is not a folder", relPath))
+				}
+				folders[relPath] = folder
+				return nil
+			}
+
+			folder, _, err := folders[relDir].MkdirAll(ctx, name)
+			if err != nil {
+				return err
+			}
+			folders[relPath] = folder
+			children[relPath] = map[string]*Node{}
+			return nil
+		}
+
+		if !fi.Mode().IsRegular() {
+			return nil
+		}
+
+		parent := folders[relDir]
+		jobs = append(jobs, &transferJob{
+			relPath: relPath,
+			run: func() (*File, bool, error) {
+				return parent.uploadFile(ctx, localPath, fi.Size(), existing, opts)
+			},
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return runTransfers(ctx, jobs, opts)
+}
+
+// uploadFile uploads the local file at localPath into folder f, overwriting
+// the existing node (if not nil) unless it is identical and
+// opts.SkipIdentical is set.
+func (f *Folder) uploadFile(ctx context.Context, localPath string, size int64, existing *Node, opts *TransferOptions) (*File, bool, error) {
+	if existing == nil && opts.SkipDuplicates {
+		file, _, err := f.UploadDeduplicated(ctx, localPath, filepath.Base(localPath))
+		if IsDuplicate(err) {
+			return file, true, nil
+		}
+		return file, false, err
+	}
+	if existing == nil {
+		file, _, err := f.Upload(ctx, localPath, filepath.Base(localPath))
+		return file, false, err
+	}
+
+	file, ok := existing.Typed().(*File)
+	if !ok {
+		return nil, false, errors.New(fmt.Sprintf("Node '%s' is not a file", *existing.Name))
+	}
+
+	if opts.SkipIdentical && sameContent(file, size, func() (string, error) { return fileMD5(localPath) }) {
+		return file, true, nil
+	}
+
+	file, _, err := file.OverwriteFromPath(ctx, localPath)
+	return file, false, err
+}
+
+// DownloadDir downloads the content of folder f into the local directory
+// localDir, creating it and its subdirectories as needed. opts may be nil.
+// Returns a result for each file. If some transfers failed, the error
+// summarizes them and the details are in the results.
+func (f *Folder) DownloadDir(ctx context.Context, localDir string, opts *TransferOptions) ([]*TransferResult, error) {
+	if opts == nil {
+		opts = &TransferOptions{}
+	}
+
+	if err := os.MkdirAll(localDir, 0777); err != nil {
+		return nil, err
+	}
+
+	// local path segments of the visited folders, by walk path
+	segments := map[string][]string{"/": nil}
+
+	var jobs []*transferJob
+	walkOpts := &WalkOptions{BasePath: "/"}
+	err := f.Walk(ctx, func(p string, n *Node, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == "/" {
+			return nil
+		}
+
+		// build the local path from the node names rather than parsing p,
+		// which would interpret names like ".."
+		name := ""
+		if n.Name != nil {
+			name = *n.Name
+		}
+		parent := strings.TrimSuffix(strings.TrimSuffix(p, pathEscaper.Replace(name)), "/")
+		if parent == "" {
+			parent = "/"
+		}
+		names := append(append([]string(nil), segments[parent]...), name)
+		relPath := strings.Join(names, "/")
+
+		if !isSafeLocalName(name) {
+			err := errors.New(fmt.Sprintf("Unsafe name '%s' for a local file", name))
+			jobs = append(jobs, &transferJob{
+				relPath: relPath,
+				run:     func() (*File, bool, error) { return nil, false, err },
+			})
+			return SkipDir
+		}
+		localPath := filepath.Join(localDir, filepath.Join(names...))
+
+		switch typed := n.Typed().(type) {
+		case *Folder:
+			if !opts.matches(relPath, true) {
+				return SkipDir
+			}
+			segments[p] = names
+			return os.MkdirAll(localPath, 0777)
+
+		case *File:
+			if opts.matches(relPath, false) {
+				jobs = append(jobs, &transferJob{
+					relPath: relPath,
+					run: func() (*File, bool, error) {
+						return typed.downloadFile(ctx, localPath, opts.SkipIdentical)
+					},
+				})
+			}
+		}
+		return nil
+	}, walkOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	return runTransfers(ctx, jobs, opts)
+}
+
+// isSafeLocalName returns whether name can be used as is for a local file,
+// without referring to another directory.
+func isSafeLocalName(name string) bool {
+	return name != "" && name != "." && name != ".." &&
+		!strings.ContainsRune(name, '/') && !strings.ContainsRune(name, os.PathSeparator)
+}
+
+// downloadFile downloads file f to localPath, replacing an existing file
+// unless it is identical and skipIdentical is set.
+func (f *File) downloadFile(ctx context.Context, localPath string, skipIdentical bool) (*File, bool, error) {
+	fi, err := os.Stat(localPath)
+	if err == nil {
+		if skipIdentical && sameContent(f, fi.Size(), func() (string, error) { return fileMD5(localPath) }) {
+			return f, true, nil
+		}
+		if err := os.Remove(localPath); err != nil {
+			return nil, false, err
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, false, err
+	}
+
+	if _, err := f.DownloadResume(ctx, localPath); err != nil {
+		return nil, false, err
+	}
+	return f, false, nil
+}
+
+// sameContent returns whether the content of file f has the given size and
+// the MD5 returned by localMD5, which is only called if the sizes match.
+func sameContent(f *File, size int64, localMD5 func() (string, error)) bool {
+	cp := f.ContentProperties
+	if cp == nil || cp.Size == nil || cp.MD5 == nil || int64(*cp.Size) != size {
+		return false
+	}
+
+	sum, err := localMD5()
+	return err == nil && sum == *cp.MD5
+}
+
+// fileMD5 returns the hex encoded MD5 of the content of the local file at
+// path.
+func fileMD5(path string) (string, error) {
+	in, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer in.Close()
+
+	hash := md5.New()
+	if _, err := io.Copy(hash, in); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,207 @@
+// Copyright (c) 2015 Serge Gebhardt. All rights reserved.
+//
+// Use of this source code is governed by the ISC
+// license that can be found in the LICENSE file.
+
+package acd
+
+import (
+	"context"
+	"crypto/md5"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeTestFiles(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		assert.NoError(t, os.MkdirAll(filepath.Dir(p), 0777))
+		assert.NoError(t, ioutil.WriteFile(p, []byte(content), 0666))
+	}
+}
+
+func TestTransfer_uploadDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go-acd")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	writeTestFiles(t, dir, map[string]string{
+		"a.txt":     "hello",
+		"sub/b.txt": "world",
+		"skip.log":  "ignored",
+	})
+
+	sum := fmt.Sprintf("%x", md5.Sum([]byte("hello")))
+	c := NewMockClientRoutes(map[string]MockResponse{
+		"/drive/v1/nodes/root/children": *NewMockResponseOkString(`{ "count": 1, "data": [
+			{ "id": "a", "name": "a.txt", "kind": "FILE", "contentProperties": { "size": 5, "md5": "` + sum + `" } }
+		] }`),
+		"GET /drive/v1/nodes":          *NewMockResponseOkString(`{ "count": 0, "data": [] }`),
+		"POST /drive/v1/nodes":         *NewMockResponseOkString(`{ "id": "sub", "name": "sub", "kind": "FOLDER" }`),
+		"POST /cdproxy/nodes":          *NewMockResponseOkString(`{ "id": "b", "name": "b.txt", "kind": "FILE" }`),
+		"PUT /cdproxy/nodes/a/content": MockResponse{Code: 500},
+	})
+	id := "root"
+	root := &Folder{&Node{Id: &id, service: c.Nodes}}
+
+	opts := &TransferOptions{Exclude: []string{"*.log"}, SkipIdentical: true}
+	results, err := root.UploadDir(context.Background(), dir, opts)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(results))
+
+	assert.Equal(t, "a.txt", results[0].Path)
+	assert.True(t, results[0].Skipped)
+	assert.Equal(t, "a", *results[0].File.Id)
+
+	assert.Equal(t, "sub/b.txt", results[1].Path)
+	assert.False(t, results[1].Skipped)
+	assert.NoError(t, results[1].Err)
+	assert.Equal(t, "b", *results[1].File.Id)
+}
+
+func TestTransfer_uploadDirSkipDuplicates(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go-acd")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	writeTestFiles(t, dir, map[string]string{"a.jpg": "hello"})
+
+	sum := fmt.Sprintf("%x", md5.Sum([]byte("hello")))
+	c := NewMockClientRoutes(map[string]MockResponse{
+		"/drive/v1/nodes/root/children": *NewMockResponseOkString(`{ "count": 0, "data": [] }`),
+		"GET /drive/v1/nodes": *NewMockResponseOkString(`{ "count": 1, "data": [
+			{ "id": "other", "name": "b.jpg", "kind": "FILE", "contentProperties": { "size": 5, "md5": "` + sum + `" } }
+		] }`),
+	})
+	id := "root"
+	root := &Folder{&Node{Id: &id, service: c.Nodes}}
+
+	results, err := root.UploadDir(context.Background(), dir, &TransferOptions{SkipDuplicates: true})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(results))
+	assert.True(t, results[0].Skipped)
+	assert.Equal(t, "other", *results[0].File.Id)
+}
+
+func TestTransfer_downloadDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go-acd")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	writeTestFiles(t, dir, map[string]string{"a.txt": "hello"})
+
+	sumA := fmt.Sprintf("%x", md5.Sum([]byte("hello")))
+	sumB := fmt.Sprintf("%x", md5.Sum([]byte("world")))
+	c := NewMockClientRoutes(map[string]MockResponse{
+		"/drive/v1/nodes/root/children": *NewMockResponseOkString(`{ "count": 3, "data": [
+			{ "id": "a", "name": "a.txt", "kind": "FILE", "contentProperties": { "size": 5, "md5": "` + sumA + `" } },
+			{ "id": "sub", "name": "sub", "kind": "FOLDER" },
+			{ "id": "c", "name": "c.jpg", "kind": "FILE" }
+		] }`),
+		"/drive/v1/nodes/sub/children": *NewMockResponseOkString(`{ "count": 1, "data": [
+			{ "id": "b", "name": "b.txt", "kind": "FILE", "contentProperties": { "size": 5, "md5": "` + sumB + `" } }
+		] }`),
+		"/cdproxy/nodes/b/content": *NewMockResponseOkString("world"),
+	})
+	id, isRoot := "root", true
+	root := &Folder{&Node{Id: &id, IsRoot: &isRoot, service: c.Nodes}}
+
+	opts := &TransferOptions{Include: []string{"*.txt"}, SkipIdentical: true}
+	results, err := root.DownloadDir(context.Background(), dir, opts)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(results))
+	for _, r := range results {
+		switch r.Path {
+		case "a.txt":
+			assert.True(t, r.Skipped)
+		case "sub/b.txt":
+			assert.False(t, r.Skipped)
+			assert.NoError(t, r.Err)
+		default:
+			t.Errorf("unexpected result %v", r.Path)
+		}
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, "sub", "b.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, "world", string(data))
+	_, err = os.Stat(filepath.Join(dir, "c.jpg"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestTransfer_downloadDirReportsFailures(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go-acd")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	c := NewMockClientRoutes(map[string]MockResponse{
+		"/drive/v1/nodes/root/children": *NewMockResponseOkString(`{ "count": 1, "data": [
+			{ "id": "a", "name": "a.txt", "kind": "FILE" }
+		] }`),
+	})
+	id, isRoot := "root", true
+	root := &Folder{&Node{Id: &id, IsRoot: &isRoot, service: c.Nodes}}
+
+	results, err := root.DownloadDir(context.Background(), dir, nil)
+
+	assert.EqualError(t, err, "1 of 1 transfers failed")
+	assert.Equal(t, 1, len(results))
+	assert.True(t, IsNotFound(results[0].Err))
+}
+
+func TestTransfer_downloadDirUnsafeNames(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go-acd")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	c := NewMockClientRoutes(map[string]MockResponse{
+		"/drive/v1/nodes/root/children": *NewMockResponseOkString(`{ "count": 3, "data": [
+			{ "id": "dot", "name": "..", "kind": "FOLDER" },
+			{ "id": "sub", "name": "sub", "kind": "FOLDER" },
+			{ "id": "x", "name": "x.txt", "kind": "FILE" }
+		] }`),
+		"/drive/v1/nodes/sub/children": *NewMockResponseOkString(`{ "count": 1, "data": [
+			{ "id": "up", "name": "..", "kind": "FILE" }
+		] }`),
+		"/cdproxy/nodes/x/content": *NewMockResponseOkString("x"),
+	})
+	id, isRoot := "root", true
+	root := &Folder{&Node{Id: &id, IsRoot: &isRoot, service: c.Nodes}}
+
+	var done []string
+	opts := &TransferOptions{
+		OnResult: func(r *TransferResult, n, total int) {
+			done = append(done, r.Path)
+			assert.Equal(t, 3, total)
+			assert.Equal(t, len(done), n)
+		},
+	}
+	results, err := root.DownloadDir(context.Background(), dir, opts)
+
+	assert.EqualError(t, err, "2 of 3 transfers failed")
+	assert.Equal(t, 3, len(done))
+	for _, r := range results {
+		switch r.Path {
+		case "..", "sub/..":
+			assert.Error(t, r.Err)
+		case "x.txt":
+			assert.NoError(t, r.Err)
+		default:
+			t.Errorf("unexpected result %v", r.Path)
+		}
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, "x.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, "x", string(data))
+	_, err = os.Stat(filepath.Join(dir, "sub", ".."+partialSuffix))
+	assert.True(t, os.IsNotExist(err))
+}
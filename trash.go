@@ -0,0 +1,48 @@
+// Copyright (c) 2015 Serge Gebhardt. All rights reserved.
+//
+// Use of this source code is governed by the ISC
+// license that can be found in the LICENSE file.
+
+package acd
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// Gets the list of all nodes in the trash.
+//
+// See: https://developer.amazon.com/public/apis/experience/cloud-drive/content/trash
+func (s *NodesService) ListTrash(ctx context.Context, opts *NodeListOptions) ([]*Node, *http.Response, error) {
+	return s.listAllNodes(ctx, "trash", opts)
+}
+
+// Trash moves node n to the trash and returns the updated node.
+func (n *Node) Trash(ctx context.Context) (*Node, *http.Response, error) {
+	url := fmt.Sprintf("trash/%s", *n.Id)
+	return n.service.sendNodeRequest(ctx, "PUT", url, nil)
+}
+
+// Restore moves node n out of the trash back to its parents and returns the
+// updated node.
+func (n *Node) Restore(ctx context.Context) (*Node, *http.Response, error) {
+	url := fmt.Sprintf("trash/%s/restore", *n.Id)
+	return n.service.sendNodeRequest(ctx, "POST", url, nil)
+}
+
+// Purge permanently deletes node n. This cannot be undone. Not all
+// applications are granted the permission to purge nodes.
+func (n *Node) Purge(ctx context.Context) (*http.Response, error) {
+	url := fmt.Sprintf("nodes/%s", *n.Id)
+	req, err := n.service.client.NewMetadataRequest(ctx, "DELETE", url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := n.service.client.Do(req, nil)
+	if err == nil && n.service.Cache != nil {
+		n.service.Cache.Remove(*n.Id)
+	}
+	return resp, err
+}
@@ -0,0 +1,66 @@
+// Copyright (c) 2015 Serge Gebhardt. All rights reserved.
+//
+// Use of this source code is governed by the ISC
+// license that can be found in the LICENSE file.
+
+package acd
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrash_listTrash(t *testing.T) {
+	c := NewMockClientSequence(
+		*NewMockResponseOkString(`{ "count": 3, "nextToken": "page2", "data": [{ "id": "a", "status": "TRASH" }, { "id": "b", "status": "TRASH" }] }`),
+		*NewMockResponseOkString(`{ "count": 3, "data": [{ "id": "c", "status": "TRASH" }] }`),
+	)
+
+	nodes, _, err := c.Nodes.ListTrash(context.Background(), nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(nodes))
+	assert.Equal(t, "c", *nodes[2].Id)
+
+	reqs := mockTransportOf(c).reqs
+	assert.Equal(t, "https://drive.amazonaws.com/drive/v1/trash", reqs[0].URL.String())
+	assert.Equal(t, "https://drive.amazonaws.com/drive/v1/trash?startToken=page2", reqs[1].URL.String())
+}
+
+func TestTrash_trashAndRestore(t *testing.T) {
+	c := NewMockClientSequence(
+		*NewMockResponseOkString(`{ "id": "fooo1", "kind": "FILE", "status": "TRASH" }`),
+		*NewMockResponseOkString(`{ "id": "fooo1", "kind": "FILE", "status": "AVAILABLE" }`),
+	)
+	id := "fooo1"
+	n := &Node{Id: &id, service: c.Nodes}
+
+	trashed, resp, err := n.Trash(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, "TRASH", *trashed.Status)
+	assert.Equal(t, "PUT", resp.Request.Method)
+	assert.Equal(t, "https://drive.amazonaws.com/drive/v1/trash/fooo1", resp.Request.URL.String())
+
+	restored, resp, err := trashed.Restore(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, "AVAILABLE", *restored.Status)
+	assert.IsType(t, &File{}, restored.Typed())
+	assert.Equal(t, "POST", resp.Request.Method)
+	assert.Equal(t, "https://drive.amazonaws.com/drive/v1/trash/fooo1/restore", resp.Request.URL.String())
+}
+
+func TestTrash_purge(t *testing.T) {
+	c := NewMockClient(MockResponse{Code: 200})
+	id := "fooo1"
+	n := &Node{Id: &id, service: c.Nodes}
+
+	resp, err := n.Purge(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, "DELETE", resp.Request.Method)
+	assert.Equal(t, "https://drive.amazonaws.com/drive/v1/nodes/fooo1", resp.Request.URL.String())
+}
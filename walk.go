@@ -0,0 +1,197 @@
+// Copyright (c) 2015 Serge Gebhardt. All rights reserved.
+//
+// Use of this source code is governed by the ISC
+// license that can be found in the LICENSE file.
+
+package acd
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+)
+
+// SkipDir can be returned by a WalkFunc to skip the children of the folder it
+// was called for.
+var SkipDir = errors.New("skip this directory")
+
+// SkipAll can be returned by a WalkFunc to stop the walk without error.
+var SkipAll = errors.New("skip everything and stop the walk")
+
+// WalkFunc is called by Folder.Walk for each node visited. path is the full
+// path of the node, with names escaped as for ResolvePath. If listing the
+// children of a folder fails, the function is called a second time for that
+// folder with the error; returning nil then continues the walk without its
+// children. Returning SkipDir skips the children of a folder, SkipAll stops
+// the walk, any other error aborts the walk and is returned by Walk. The
+// function is never called concurrently.
+type WalkFunc func(path string, node *Node, err error) error
+
+// WalkOptions holds the options of Folder.Walk.
+type WalkOptions struct {
+	// Maximum number of concurrent listing requests. Defaults to 4.
+	Concurrency int
+
+	// Maximum depth of the nodes visited, relative to the starting folder
+	// (depth 0). Zero means unlimited.
+	MaxDepth int
+
+	// If set, the WalkFunc is only called for nodes of this kind (e.g.
+	// KindFile). Folders are traversed nonetheless.
+	Kind string
+
+	// Path of the starting folder. If empty, it is resolved using
+	// Node.GetPaths (taking the first path if there are several).
+	BasePath string
+
+	// Options for listing the children, e.g. a filter. Folders not matching
+	// the filter are not traversed.
+	ListOptions *NodeListOptions
+}
+
+// walkListing is the result of listing the children of a folder.
+type walkListing struct {
+	folder   *Folder
+	path     string
+	depth    int
+	children []*Node
+	err      error
+}
+
+// Walk walks the folder hierarchy rooted at folder f, calling fn for each node,
+// including f. The children of several folders are listed concurrently, so
+// the order of the nodes is only guaranteed to visit a folder before its
+// children. opts may be nil.
+func (f *Folder) Walk(ctx context.Context, fn WalkFunc, opts *WalkOptions) error {
+	if opts == nil {
+		opts = &WalkOptions{}
+	}
+	concurrency := opts.Concurrency
+	if concurrency < 1 {
+		concurrency = 4
+	}
+
+	base := opts.BasePath
+	if base == "" {
+		var err error
+		if base, err = f.basePath(ctx); err != nil {
+			return err
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var queue []*walkListing
+	inflight := 0
+	results := make(chan *walkListing)
+
+	// visit calls fn for node n and queues the listing of its children if it
+	// is a folder. Returns whether to stop.
+	var walkErr error
+	visit := func(n *Node, p string, depth int) bool {
+		folder, isFolder := n.Typed().(*Folder)
+		if n == f.Node {
+			folder, isFolder = f, true
+		}
+
+		var err error
+		if opts.Kind == "" || (n.Kind != nil && *n.Kind == opts.Kind) {
+			err = fn(p, n, nil)
+		}
+		switch {
+		case err == SkipDir:
+			return false
+		case err == SkipAll:
+			return true
+		case err != nil:
+			walkErr = err
+			return true
+		}
+
+		if isFolder && (opts.MaxDepth == 0 || depth < opts.MaxDepth) {
+			queue = append(queue, &walkListing{folder: folder, path: p, depth: depth})
+		}
+		return false
+	}
+
+	stop := visit(f.Node, base, 0)
+	for !stop && (len(queue) > 0 || inflight > 0) {
+		for len(queue) > 0 && inflight < concurrency {
+			l := queue[0]
+			queue = queue[1:]
+			inflight++
+			go func() {
+				var listOpts *NodeListOptions
+				if opts.ListOptions != nil {
+					o := *opts.ListOptions
+					listOpts = &o
+				}
+				l.children, _, l.err = l.folder.GetAllChildren(ctx, listOpts)
+				results <- l
+			}()
+		}
+
+		l := <-results
+		inflight--
+
+		if l.err != nil {
+			err := fn(l.path, l.folder.Node, l.err)
+			if err == SkipAll {
+				break
+			}
+			if err != nil && err != SkipDir {
+				walkErr = err
+				break
+			}
+			continue
+		}
+
+		sort.Sort(nodesByName(l.children))
+		for _, n := range l.children {
+			name := ""
+			if n.Name != nil {
+				name = *n.Name
+			}
+			p := strings.TrimSuffix(l.path, "/") + "/" + pathEscaper.Replace(name)
+			if stop = visit(n, p, l.depth+1); stop {
+				break
+			}
+		}
+	}
+
+	// abort and wait for the listings still running
+	cancel()
+	for ; inflight > 0; inflight-- {
+		<-results
+	}
+
+	return walkErr
+}
+
+// basePath returns the path of folder f, with its names escaped as by
+// JoinPath.
+func (f *Folder) basePath(ctx context.Context) (string, error) {
+	if f.IsRoot != nil && *f.IsRoot {
+		return "/", nil
+	}
+
+	paths, err := f.GetPaths(ctx)
+	if err != nil {
+		return "", err
+	}
+	if len(paths) == 0 {
+		return "", errors.New("Cannot determine the path of the folder")
+	}
+	return paths[0], nil
+}
+
+// nodesByName sorts nodes by name.
+type nodesByName []*Node
+
+func (s nodesByName) Len() int      { return len(s) }
+func (s nodesByName) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s nodesByName) Less(i, j int) bool {
+	return s[i].Name != nil && (s[j].Name == nil || *s[i].Name < *s[j].Name)
+}
@@ -0,0 +1,134 @@
+// Copyright (c) 2015 Serge Gebhardt. All rights reserved.
+//
+// Use of this source code is governed by the ISC
+// license that can be found in the LICENSE file.
+
+package acd
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newWalkTree returns a mocked client serving the following tree:
+//
+//	/
+//	/a/
+//	/a/b/
+//	/a/b/z.txt
+//	/a/y.txt
+//	/c/
+//	/x.txt
+func newWalkTree() (*Client, *Folder) {
+	const prefix = "/drive/v1/nodes/"
+	c := NewMockClientRoutes(map[string]MockResponse{
+		prefix + "root/children": *NewMockResponseOkString(`{ "count": 3, "data": [
+			{ "id": "x", "name": "x.txt", "kind": "FILE" },
+			{ "id": "a", "name": "a", "kind": "FOLDER" },
+			{ "id": "c", "name": "c", "kind": "FOLDER" }
+		] }`),
+		prefix + "a/children": *NewMockResponseOkString(`{ "count": 2, "data": [
+			{ "id": "b", "name": "b", "kind": "FOLDER" },
+			{ "id": "y", "name": "y.txt", "kind": "FILE" }
+		] }`),
+		prefix + "b/children": *NewMockResponseOkString(`{ "count": 1, "data": [
+			{ "id": "z", "name": "z.txt", "kind": "FILE" }
+		] }`),
+		prefix + "c/children": *NewMockResponseOkString(`{ "count": 0, "data": [] }`),
+	})
+
+	id, kind, isRoot := "root", KindFolder, true
+	root := &Folder{&Node{Id: &id, Kind: &kind, IsRoot: &isRoot, service: c.Nodes}}
+	return c, root
+}
+
+// walkPaths walks root and returns the sorted paths visited.
+func walkPaths(t *testing.T, root *Folder, opts *WalkOptions, skip string) []string {
+	var mu sync.Mutex
+	var paths []string
+	err := root.Walk(context.Background(), func(path string, n *Node, err error) error {
+		assert.NoError(t, err)
+		mu.Lock()
+		paths = append(paths, path)
+		mu.Unlock()
+		if path == skip {
+			return SkipDir
+		}
+		return nil
+	}, opts)
+	assert.NoError(t, err)
+
+	sort.Strings(paths)
+	return paths
+}
+
+func TestWalk_all(t *testing.T) {
+	_, root := newWalkTree()
+
+	paths := walkPaths(t, root, nil, "")
+
+	assert.Equal(t, []string{"/", "/a", "/a/b", "/a/b/z.txt", "/a/y.txt", "/c", "/x.txt"}, paths)
+}
+
+func TestWalk_skipDir(t *testing.T) {
+	c, root := newWalkTree()
+
+	paths := walkPaths(t, root, &WalkOptions{Concurrency: 1}, "/a")
+
+	assert.Equal(t, []string{"/", "/a", "/c", "/x.txt"}, paths)
+	assert.Equal(t, 2, len(mockRouteTransportOf(c).reqs))
+}
+
+func TestWalk_maxDepthAndKind(t *testing.T) {
+	_, root := newWalkTree()
+
+	paths := walkPaths(t, root, &WalkOptions{MaxDepth: 2, Kind: KindFile}, "")
+
+	assert.Equal(t, []string{"/a/y.txt", "/x.txt"}, paths)
+}
+
+func TestWalk_escapedBasePath(t *testing.T) {
+	const prefix = "/drive/v1/nodes/"
+	c := NewMockClientRoutes(map[string]MockResponse{
+		prefix + "root": *NewMockResponseOkString(`{ "id": "root", "kind": "FOLDER", "isRoot": true }`),
+		prefix + "odd/children": *NewMockResponseOkString(`{ "count": 1, "data": [
+			{ "id": "z", "name": "z.txt", "kind": "FILE" }
+		] }`),
+	})
+	id, name, kind := "odd", `a/b\c`, KindFolder
+	folder := &Folder{&Node{Id: &id, Name: &name, Kind: &kind, Parents: []string{"root"}, service: c.Nodes}}
+
+	paths := walkPaths(t, folder, nil, "")
+
+	assert.Equal(t, []string{`/a\/b\\c`, `/a\/b\\c/z.txt`}, paths)
+	names, err := SplitPath(paths[1])
+	assert.NoError(t, err)
+	assert.Equal(t, []string{name, "z.txt"}, names)
+}
+
+func TestWalk_error(t *testing.T) {
+	c := NewMockClientRoutes(map[string]MockResponse{
+		"/drive/v1/nodes/root/children": *NewMockResponseOkString(`{ "count": 1, "data": [
+			{ "id": "gone", "name": "gone", "kind": "FOLDER" }
+		] }`),
+	})
+	id, kind, isRoot := "root", KindFolder, true
+	root := &Folder{&Node{Id: &id, Kind: &kind, IsRoot: &isRoot, service: c.Nodes}}
+
+	abort := errors.New("abort")
+	err := root.Walk(context.Background(), func(path string, n *Node, err error) error {
+		if err != nil {
+			assert.Equal(t, "/gone", path)
+			assert.True(t, IsNotFound(err))
+			return abort
+		}
+		return nil
+	}, nil)
+
+	assert.Equal(t, abort, err)
+}